@@ -0,0 +1,356 @@
+/*
+Copyright © 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package approval implements store of must-gather requests that wait for
+// approval by second engineer before they are sent to the controller.
+package approval
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Status of must-gather request
+type Status string
+
+// States the request goes through. Request in the approving state is being
+// sent to the controller; it returns to pending state when that fails. When
+// the service is stopped during approval, it's not known whether must-gather
+// has been triggered, so the request is interrupted and needs to be approved
+// again or rejected by user that checks triggers of the cluster.
+const (
+	StatusPending     Status = "pending"
+	StatusApproving   Status = "approving"
+	StatusInterrupted Status = "interrupted"
+	StatusApproved    Status = "approved"
+	StatusRejected    Status = "rejected"
+	StatusCancelled   Status = "cancelled"
+)
+
+// Delivery is result of sending approved request to the controller
+type Delivery int
+
+// Results of sending approved request to the controller
+const (
+	Delivered Delivery = iota
+	NotDelivered
+	DeliveryUnknown
+)
+
+// Errors returned when request can't be approved, rejected or cancelled
+var (
+	ErrNotFound       = errors.New("Must-gather request not found")
+	ErrNotPending     = errors.New("Must-gather request is not waiting for approval")
+	ErrSelfApproval   = errors.New("Must-gather request needs to be approved or rejected by another user")
+	ErrNotRequester   = errors.New("Must-gather request can be cancelled only by user that submitted it")
+	ErrMissingDecider = errors.New("User name of approver is not known")
+)
+
+// Request represents must-gather requested by user.
+//
+//	ID: unique ID of request
+//	ClusterID: ID of cluster must-gather is requested for
+//	ClusterName: name of cluster must-gather is requested for
+//	Reason: why the data need to be gathered
+//	Link: link to the support case or bug
+//	RequestedBy: user that submitted the request
+//	RequestedAt: time the request was submitted
+//	Status: current state of the request
+//	DecidedBy: user that approved, rejected or cancelled the request
+//	DecidedAt: time the request was approved, rejected or cancelled
+//	Comment: reason for rejection or cancellation
+type Request struct {
+	ID          int        `json:"id"`
+	ClusterID   string     `json:"cluster_id"`
+	ClusterName string     `json:"cluster_name"`
+	Reason      string     `json:"reason"`
+	Link        string     `json:"link"`
+	RequestedBy string     `json:"requested_by"`
+	RequestedAt time.Time  `json:"requested_at"`
+	Status      Status     `json:"status"`
+	DecidedBy   string     `json:"decided_by,omitempty"`
+	DecidedAt   *time.Time `json:"decided_at,omitempty"`
+	Comment     string     `json:"comment,omitempty"`
+}
+
+// Store keeps must-gather requests. When file name is specified, requests
+// are saved to JSON file after each change, so pending requests survive
+// restart of the service.
+type Store struct {
+	filename string
+
+	mutex    sync.Mutex
+	requests map[int]*Request
+	lastID   int
+}
+
+// NewStore constructs store and loads requests from file if it exists
+func NewStore(filename string) (*Store, error) {
+	store := &Store{
+		filename: filename,
+		requests: map[int]*Request{},
+	}
+	err := store.load()
+	if err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// load reads requests from file
+func (s *Store) load() error {
+	if s.filename == "" {
+		return nil
+	}
+	content, err := ioutil.ReadFile(s.filename)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("Unable to read must-gather requests from %s: %v", s.filename, err)
+	}
+
+	var requests []*Request
+	err = json.Unmarshal(content, &requests)
+	if err != nil {
+		return fmt.Errorf("Unable to parse must-gather requests in %s: %v", s.filename, err)
+	}
+	for _, request := range requests {
+		// approval has been interrupted by restart
+		if request.Status == StatusApproving {
+			request.Status = StatusInterrupted
+		}
+		s.requests[request.ID] = request
+		if request.ID > s.lastID {
+			s.lastID = request.ID
+		}
+	}
+	return nil
+}
+
+// save writes all requests to file, must be called with mutex locked. New
+// file is written first and then renamed, so the file is never truncated.
+func (s *Store) save() error {
+	if s.filename == "" {
+		return nil
+	}
+	content, err := json.MarshalIndent(s.sorted(), "", "  ")
+	if err != nil {
+		return err
+	}
+
+	temporary, err := ioutil.TempFile(filepath.Dir(s.filename), filepath.Base(s.filename)+".*")
+	if err != nil {
+		return err
+	}
+	_, err = temporary.Write(content)
+	if closeErr := temporary.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(temporary.Name())
+		return err
+	}
+	return os.Rename(temporary.Name(), s.filename)
+}
+
+// sorted returns copies of all requests, the newest first, must be called
+// with mutex locked
+func (s *Store) sorted() []Request {
+	requests := make([]Request, 0, len(s.requests))
+	for _, request := range s.requests {
+		requests = append(requests, *request)
+	}
+	sort.Slice(requests, func(i, j int) bool {
+		return requests[i].ID > requests[j].ID
+	})
+	return requests
+}
+
+// Submit stores new request waiting for approval
+func (s *Store) Submit(request Request) (Request, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.lastID++
+	request.ID = s.lastID
+	request.Status = StatusPending
+	request.RequestedAt = time.Now().UTC()
+	s.requests[request.ID] = &request
+
+	err := s.save()
+	if err != nil {
+		delete(s.requests, request.ID)
+		return Request{}, fmt.Errorf("Unable to save must-gather request: %v", err)
+	}
+	return request, nil
+}
+
+// Get returns request with given ID
+func (s *Store) Get(id int) (Request, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	request, found := s.requests[id]
+	if !found {
+		return Request{}, ErrNotFound
+	}
+	return *request, nil
+}
+
+// List returns all requests, the newest first
+func (s *Store) List() []Request {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.sorted()
+}
+
+// Waiting checks whether the request waits for decision
+func (r Request) Waiting() bool {
+	return r.Status == StatusPending || r.Status == StatusInterrupted
+}
+
+// pendingRequest finds request that can be decided by given user, must be
+// called with mutex locked
+func (s *Store) pendingRequest(id int, decider string) (*Request, error) {
+	request, found := s.requests[id]
+	switch {
+	case !found:
+		return nil, ErrNotFound
+	case !request.Waiting():
+		return nil, ErrNotPending
+	case decider == "":
+		return nil, ErrMissingDecider
+	case decider == request.RequestedBy:
+		return nil, ErrSelfApproval
+	}
+	return request, nil
+}
+
+// StartApproval marks the request as being approved, so no other user can
+// approve it at the same time. The state is saved before the request is sent
+// to the controller, so approval interrupted by restart is recognized.
+// FinishApproval needs to be called when the request has been sent to the
+// controller.
+func (s *Store) StartApproval(id int, approver string) (Request, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	request, err := s.pendingRequest(id, approver)
+	if err != nil {
+		return Request{}, err
+	}
+	status := request.Status
+	request.Status = StatusApproving
+
+	err = s.save()
+	if err != nil {
+		request.Status = status
+		return Request{}, fmt.Errorf("Unable to save must-gather request: %v", err)
+	}
+	return *request, nil
+}
+
+// FinishApproval records result of sending the approved request to the
+// controller. The request is approved when it has been sent, it waits for
+// approval again only when it surely has not reached the controller. In all
+// other cases it's not known whether must-gather has been triggered, so the
+// request is interrupted the same way as by restart during approval.
+func (s *Store) FinishApproval(id int, approver string, delivery Delivery) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	request, found := s.requests[id]
+	if !found || request.Status != StatusApproving {
+		return ErrNotPending
+	}
+	switch delivery {
+	case Delivered:
+		now := time.Now().UTC()
+		request.Status = StatusApproved
+		request.DecidedBy = approver
+		request.DecidedAt = &now
+	case NotDelivered:
+		request.Status = StatusPending
+	default:
+		request.Status = StatusInterrupted
+	}
+
+	// must-gather may have been triggered already, so the state is changed
+	// in memory even when it can't be saved
+	err := s.save()
+	if err != nil {
+		return fmt.Errorf("Unable to save must-gather request: %v", err)
+	}
+	return nil
+}
+
+// Reject marks the request as rejected
+func (s *Store) Reject(id int, rejecter string, comment string) (Request, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	request, err := s.pendingRequest(id, rejecter)
+	if err != nil {
+		return Request{}, err
+	}
+	return s.decide(request, StatusRejected, rejecter, comment)
+}
+
+// Cancel marks the request as cancelled, only user that submitted the
+// request can cancel it
+func (s *Store) Cancel(id int, requester string, comment string) (Request, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	request, found := s.requests[id]
+	switch {
+	case !found:
+		return Request{}, ErrNotFound
+	case !request.Waiting():
+		return Request{}, ErrNotPending
+	case requester == "" || requester != request.RequestedBy:
+		return Request{}, ErrNotRequester
+	}
+	return s.decide(request, StatusCancelled, requester, comment)
+}
+
+// decide changes state of request that won't be sent to the controller, must
+// be called with mutex locked. The original request is kept when the change
+// can't be saved.
+func (s *Store) decide(request *Request, status Status, decider string, comment string) (Request, error) {
+	now := time.Now().UTC()
+	decided := *request
+	decided.Status = status
+	decided.DecidedBy = decider
+	decided.DecidedAt = &now
+	decided.Comment = comment
+	s.requests[request.ID] = &decided
+
+	err := s.save()
+	if err != nil {
+		s.requests[request.ID] = request
+		return Request{}, fmt.Errorf("Unable to save must-gather request: %v", err)
+	}
+	return decided, nil
+}
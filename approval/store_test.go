@@ -0,0 +1,186 @@
+/*
+Copyright © 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package approval
+
+import (
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// submitTestRequest stores request submitted by jdoe
+func submitTestRequest(t *testing.T, store *Store) Request {
+	request, err := store.Submit(Request{ClusterID: "1", ClusterName: "cluster-a", Reason: "crash loop", RequestedBy: "jdoe"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return request
+}
+
+// TestApproval checks that request is approved by another user only
+func TestApproval(t *testing.T) {
+	store, err := NewStore("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	request := submitTestRequest(t, store)
+
+	_, err = store.StartApproval(request.ID, "jdoe")
+	if !errors.Is(err, ErrSelfApproval) {
+		t.Fatalf("requester should not approve the request: %v", err)
+	}
+	_, err = store.StartApproval(request.ID, "")
+	if !errors.Is(err, ErrMissingDecider) {
+		t.Fatalf("unknown user should not approve the request: %v", err)
+	}
+
+	_, err = store.StartApproval(request.ID, "asmith")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = store.StartApproval(request.ID, "bwhite")
+	if !errors.Is(err, ErrNotPending) {
+		t.Fatalf("request should not be approved twice: %v", err)
+	}
+
+	// sending to the controller failed, request waits for approval again
+	err = store.FinishApproval(request.ID, "asmith", NotDelivered)
+	if err != nil {
+		t.Fatal(err)
+	}
+	request, _ = store.Get(request.ID)
+	if request.Status != StatusPending || request.DecidedAt != nil {
+		t.Fatalf("request should be pending, got %+v", request)
+	}
+
+	// controller did not respond, must-gather might have been triggered
+	_, err = store.StartApproval(request.ID, "asmith")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.FinishApproval(request.ID, "asmith", DeliveryUnknown)
+	if err != nil {
+		t.Fatal(err)
+	}
+	request, _ = store.Get(request.ID)
+	if request.Status != StatusInterrupted {
+		t.Fatalf("request should be interrupted, got %+v", request)
+	}
+
+	_, err = store.StartApproval(request.ID, "asmith")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.FinishApproval(request.ID, "asmith", Delivered)
+	if err != nil {
+		t.Fatal(err)
+	}
+	request, _ = store.Get(request.ID)
+	if request.Status != StatusApproved || request.DecidedBy != "asmith" || request.DecidedAt == nil {
+		t.Fatalf("request should be approved, got %+v", request)
+	}
+}
+
+// TestRejectAndCancel checks that request is rejected by another user and
+// cancelled by requester only
+func TestRejectAndCancel(t *testing.T) {
+	store, err := NewStore("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rejected := submitTestRequest(t, store)
+	_, err = store.Reject(rejected.ID, "jdoe", "")
+	if !errors.Is(err, ErrSelfApproval) {
+		t.Fatalf("requester should not reject the request: %v", err)
+	}
+	rejected, err = store.Reject(rejected.ID, "asmith", "no customer ACK")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rejected.Status != StatusRejected || rejected.Comment != "no customer ACK" {
+		t.Fatalf("request should be rejected, got %+v", rejected)
+	}
+
+	cancelled := submitTestRequest(t, store)
+	_, err = store.Cancel(cancelled.ID, "asmith", "")
+	if !errors.Is(err, ErrNotRequester) {
+		t.Fatalf("other user should not cancel the request: %v", err)
+	}
+	cancelled, err = store.Cancel(cancelled.ID, "jdoe", "wrong cluster")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cancelled.Status != StatusCancelled || cancelled.DecidedBy != "jdoe" {
+		t.Fatalf("request should be cancelled, got %+v", cancelled)
+	}
+	_, err = store.StartApproval(cancelled.ID, "asmith")
+	if !errors.Is(err, ErrNotPending) {
+		t.Fatalf("cancelled request should not be approved: %v", err)
+	}
+	_, err = store.Cancel(rejected.ID, "jdoe", "")
+	if !errors.Is(err, ErrNotPending) {
+		t.Fatalf("rejected request should not be cancelled: %v", err)
+	}
+}
+
+// TestInterruptedApproval checks that approval in progress is saved and
+// needs to be resolved after restart
+func TestInterruptedApproval(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "requests.json")
+	store, err := NewStore(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	request := submitTestRequest(t, store)
+	_, err = store.StartApproval(request.ID, "asmith")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// service stopped before the controller responded
+	restarted, err := NewStore(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	request, err = restarted.Get(request.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if request.Status != StatusInterrupted {
+		t.Fatalf("request should be interrupted, got %s", request.Status)
+	}
+
+	// interrupted request can be rejected once triggers are checked
+	_, err = restarted.Reject(request.ID, "asmith", "must-gather has been triggered")
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestPendingRequestJSON checks that decision is omitted for pending request
+func TestPendingRequestJSON(t *testing.T) {
+	encoded, err := json.Marshal(Request{ID: 1, Status: StatusPending})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(encoded), "decided_at") {
+		t.Errorf("decided_at should be omitted: %s", encoded)
+	}
+}
@@ -0,0 +1,60 @@
+/*
+Copyright © 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"embed"
+	"github.com/tisnik/insights-operator-web-ui/logging"
+	"io/fs"
+	"os"
+)
+
+// directory with HTML templates and static assets in the source tree
+const assetsDirectory = "html"
+
+// embeddedAssets contains all HTML templates, CSS and JavaScript files, so
+// the binary can be started from any directory
+//
+//go:embed html
+var embeddedAssets embed.FS
+
+// assets is a file system with HTML templates and static assets used by all
+// handlers. It is either the embedded file system or a directory on disk.
+var assets fs.FS
+
+// initAssets selects the file system with assets. Loading assets from disk
+// is useful during development, as changes are visible without rebuilding
+// the binary.
+func initAssets(loadFromDisk bool, directory string) error {
+	if loadFromDisk {
+		if directory == "" {
+			directory = assetsDirectory
+		}
+		logging.Info(context.Background(), "Loading assets from directory", "directory", directory)
+		assets = os.DirFS(directory)
+		return nil
+	}
+
+	logging.Info(context.Background(), "Using embedded assets")
+	embedded, err := fs.Sub(embeddedAssets, assetsDirectory)
+	if err != nil {
+		return err
+	}
+	assets = embedded
+	return nil
+}
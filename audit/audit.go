@@ -0,0 +1,204 @@
+/*
+Copyright © 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package audit implements append-only log of write actions performed
+// through the web UI. Events are stored in JSON lines file that is rotated
+// when it grows over the configured size.
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Default settings used when no other values are configured
+const (
+	DefaultMaxSize    = 10 * 1024 * 1024
+	DefaultMaxBackups = 5
+)
+
+// Results of recorded actions
+const (
+	ResultSuccess = "success"
+	ResultFailure = "failure"
+)
+
+// Event represents one action performed by user.
+//
+//	Timestamp: time when the action was finished
+//	Actor: user that performed the action
+//	Action: name of operation, for example enable_cluster_configuration
+//	Target: cluster name, profile, configuration or trigger ID the action changed
+//	Parameters: other request parameters
+//	ControllerStatus: HTTP status returned by the controller, 0 if unknown
+//	Result: success or failure
+//	Error: error message for failed actions
+//	RequestID: ID of HTTP request the action was performed by
+type Event struct {
+	Timestamp        time.Time         `json:"timestamp"`
+	Actor            string            `json:"actor"`
+	Action           string            `json:"action"`
+	Target           string            `json:"target,omitempty"`
+	Parameters       map[string]string `json:"parameters,omitempty"`
+	ControllerStatus int               `json:"controller_status,omitempty"`
+	Result           string            `json:"result"`
+	Error            string            `json:"error,omitempty"`
+	RequestID        string            `json:"request_id,omitempty"`
+}
+
+// Log appends events to JSON lines file. When the file grows over maximum
+// size, it is renamed to file.1 (older backups to file.2 etc.) and a new
+// file is started.
+type Log struct {
+	filename   string
+	maxSize    int64
+	maxBackups int
+
+	// mutex serializes writes, rotation mutex is held for writing only
+	// when files are renamed, so searches don't block recording of events
+	mutex         sync.Mutex
+	rotationMutex sync.RWMutex
+	file          *os.File
+	size          int64
+}
+
+// Open opens (or creates) the audit log file
+func Open(filename string, maxSize int64, maxBackups int) (*Log, error) {
+	if maxSize <= 0 {
+		maxSize = DefaultMaxSize
+	}
+	if maxBackups <= 0 {
+		maxBackups = DefaultMaxBackups
+	}
+
+	l := &Log{
+		filename:   filename,
+		maxSize:    maxSize,
+		maxBackups: maxBackups,
+	}
+	err := l.open()
+	if err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// open opens the current file for appending, must be called with mutex
+// locked
+func (l *Log) open() error {
+	file, err := os.OpenFile(l.filename, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return fmt.Errorf("Unable to open audit log %s: %v", l.filename, err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return fmt.Errorf("Unable to read size of audit log %s: %v", l.filename, err)
+	}
+	l.file = file
+	l.size = info.Size()
+	return nil
+}
+
+// backupName returns name of n-th rotated file
+func (l *Log) backupName(n int) string {
+	return l.filename + "." + strconv.Itoa(n)
+}
+
+// rotate renames the current file to the first backup and opens new file,
+// must be called with mutex locked. The current file is opened again when
+// the files can't be renamed.
+func (l *Log) rotate() error {
+	l.rotationMutex.Lock()
+	defer l.rotationMutex.Unlock()
+
+	err := l.file.Close()
+	l.file = nil
+	if err == nil {
+		err = l.renameFiles()
+	}
+
+	openErr := l.open()
+	if err != nil {
+		return err
+	}
+	return openErr
+}
+
+// renameFiles renames the current file and all backups, the oldest backup
+// is overwritten
+func (l *Log) renameFiles() error {
+	for n := l.maxBackups - 1; n >= 1; n-- {
+		err := os.Rename(l.backupName(n), l.backupName(n+1))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return os.Rename(l.filename, l.backupName(1))
+}
+
+// Record appends event to the log. Timestamp is set when it is not
+// specified.
+func (l *Log) Record(event Event) error {
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now().UTC()
+	}
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	// event is still appended to the current file when rotation failed
+	var rotationErr error
+	if l.size > 0 && l.size+int64(len(line)) > l.maxSize {
+		err = l.rotate()
+		if err != nil {
+			rotationErr = fmt.Errorf("Unable to rotate audit log %s: %v", l.filename, err)
+		}
+	}
+	if l.file == nil {
+		return rotationErr
+	}
+
+	n, err := l.file.Write(line)
+	l.size += int64(n)
+	if err != nil {
+		return err
+	}
+	err = l.file.Sync()
+	if err != nil {
+		return err
+	}
+	return rotationErr
+}
+
+// Close closes the current file
+func (l *Log) Close() error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.file == nil {
+		return nil
+	}
+	return l.file.Close()
+}
@@ -0,0 +1,139 @@
+/*
+Copyright © 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package audit
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+// openTestLog opens log in temporary directory
+func openTestLog(t *testing.T, maxSize int64, maxBackups int) *Log {
+	l, err := Open(filepath.Join(t.TempDir(), "audit.jsonl"), maxSize, maxBackups)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		err := l.Close()
+		if err != nil {
+			t.Error(err)
+		}
+	})
+	return l
+}
+
+// recordEvents records events with targets 0..count-1
+func recordEvents(t *testing.T, l *Log, count int) {
+	for i := 0; i < count; i++ {
+		err := l.Record(Event{Actor: "jdoe", Action: "enable_cluster_configuration", Target: strconv.Itoa(i), Result: ResultSuccess})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// TestRotation checks that all events are found in the current file and
+// backups and the oldest backups are removed
+func TestRotation(t *testing.T) {
+	l := openTestLog(t, 300, 2)
+	recordEvents(t, l, 10)
+
+	_, err := os.Stat(l.backupName(2))
+	if err != nil {
+		t.Fatalf("second backup should exist: %v", err)
+	}
+	_, err = os.Stat(l.backupName(3))
+	if !os.IsNotExist(err) {
+		t.Fatalf("third backup should not exist: %v", err)
+	}
+
+	events, err := l.Search(Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) == 0 || len(events) >= 10 {
+		t.Fatalf("oldest events should be removed, found %d events", len(events))
+	}
+	if events[0].Target != "9" {
+		t.Errorf("newest event should be first, got %v", events[0])
+	}
+}
+
+// TestFailedRotation checks that events are still recorded to the current
+// file when it can't be rotated
+func TestFailedRotation(t *testing.T) {
+	l := openTestLog(t, 300, 1)
+
+	// directory that is not empty can't be replaced by the current file
+	err := os.MkdirAll(filepath.Join(l.backupName(1), "blocked"), 0700)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var rotationErr error
+	for i := 0; i < 10 && rotationErr == nil; i++ {
+		rotationErr = l.Record(Event{Actor: "jdoe", Action: "enable_cluster_configuration", Result: ResultSuccess})
+	}
+	if rotationErr == nil {
+		t.Fatal("rotation should fail")
+	}
+
+	// current file has been opened again and the event is appended to it
+	_ = l.Record(Event{Actor: "jdoe", Action: "activate_trigger", Result: ResultSuccess})
+	err = os.RemoveAll(l.backupName(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	events, err := l.Search(Filter{Action: "activate_trigger"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 {
+		t.Errorf("event should be recorded after failed rotation, found %d events", len(events))
+	}
+}
+
+// TestSearchDuringRecord checks that search can run together with
+// recording and rotation
+func TestSearchDuringRecord(t *testing.T) {
+	l := openTestLog(t, 1000, 3)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		recordEvents(t, l, 100)
+	}()
+	for i := 0; i < 20; i++ {
+		_, err := l.Search(Filter{Actor: "jdoe"})
+		if err != nil {
+			t.Error(err)
+		}
+	}
+	wg.Wait()
+
+	events, err := l.Search(Filter{Target: "99"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 {
+		t.Errorf("expected the last event, found %d events", len(events))
+	}
+}
@@ -0,0 +1,136 @@
+/*
+Copyright © 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package audit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"github.com/tisnik/insights-operator-web-ui/logging"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultSearchLimit is maximum number of events returned by search when no
+// limit is specified
+const DefaultSearchLimit = 500
+
+// Filter selects events returned by search. Empty fields match all events.
+//
+//	Actor: user that performed the action
+//	Action: name of operation
+//	Target: part of target cluster name, configuration or trigger ID
+//	Result: success or failure
+//	From: the oldest event
+//	To: the newest event
+//	Limit: maximum number of returned events
+type Filter struct {
+	Actor  string
+	Action string
+	Target string
+	Result string
+	From   time.Time
+	To     time.Time
+	Limit  int
+}
+
+// matches checks whether event satisfies the filter
+func (f Filter) matches(event Event) bool {
+	switch {
+	case f.Actor != "" && !strings.EqualFold(f.Actor, event.Actor):
+		return false
+	case f.Action != "" && f.Action != event.Action:
+		return false
+	case f.Target != "" && !strings.Contains(event.Target, f.Target):
+		return false
+	case f.Result != "" && f.Result != event.Result:
+		return false
+	case !f.From.IsZero() && event.Timestamp.Before(f.From):
+		return false
+	case !f.To.IsZero() && event.Timestamp.After(f.To):
+		return false
+	}
+	return true
+}
+
+// readEvents reads all events matching filter from one file. Lines that
+// can't be parsed are skipped.
+func readEvents(filename string, filter Filter) ([]Event, error) {
+	file, err := os.Open(filename) // #nosec G304
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer func() {
+		err := file.Close()
+		if err != nil {
+			logging.Warn(context.Background(), "Unable to close audit log file", "file", filename, "error", err)
+		}
+	}()
+
+	var events []Event
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		var event Event
+		if json.Unmarshal(scanner.Bytes(), &event) != nil {
+			continue
+		}
+		if filter.matches(event) {
+			events = append(events, event)
+		}
+	}
+	return events, scanner.Err()
+}
+
+// Search returns events matching the filter from the current file and all
+// backups, the newest events first. Files are not rotated during search.
+func (l *Log) Search(filter Filter) ([]Event, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = DefaultSearchLimit
+	}
+
+	// events can be recorded during search, but files are not renamed
+	l.rotationMutex.RLock()
+	defer l.rotationMutex.RUnlock()
+
+	filenames := []string{l.filename}
+	for n := 1; n <= l.maxBackups; n++ {
+		filenames = append(filenames, l.backupName(n))
+	}
+
+	var events []Event
+	for _, filename := range filenames {
+		found, err := readEvents(filename, filter)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, found...)
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Timestamp.After(events[j].Timestamp)
+	})
+	if len(events) > filter.Limit {
+		events = events[:filter.Limit]
+	}
+	return events, nil
+}
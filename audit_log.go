@@ -0,0 +1,220 @@
+/*
+Copyright © 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"github.com/tisnik/insights-operator-web-ui/audit"
+	"github.com/tisnik/insights-operator-web-ui/client"
+	"github.com/tisnik/insights-operator-web-ui/logging"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// format of dates in audit log filter
+const auditDateFormat = "2006-01-02"
+
+// actor recorded for actions performed when user login is disabled and no
+// user name has been entered
+const anonymousActor = "anonymous"
+
+// AuditConfiguration represents configuration of the audit log.
+//
+//	Enabled: write actions are recorded when enabled
+//	File: JSON lines file with events
+//	MaxSize: size in bytes the file is rotated at
+//	MaxBackups: number of rotated files that are kept
+type AuditConfiguration struct {
+	Enabled    bool
+	File       string
+	MaxSize    int64
+	MaxBackups int
+}
+
+// auditLog records all write actions, it is nil when audit is disabled
+var auditLog *audit.Log
+
+// initAudit opens the audit log when it is enabled
+func initAudit(configuration AuditConfiguration) error {
+	if !configuration.Enabled {
+		logging.Info(context.Background(), "Audit log is disabled")
+		return nil
+	}
+
+	l, err := audit.Open(configuration.File, configuration.MaxSize, configuration.MaxBackups)
+	if err != nil {
+		return err
+	}
+	auditLog = l
+	logging.Info(context.Background(), "Recording write actions to audit log", "file", configuration.File)
+	return nil
+}
+
+// auditActor returns user that performs the action
+func auditActor(request *http.Request) string {
+	actor := formUsername(request)
+	if actor == "" {
+		return anonymousActor
+	}
+	return actor
+}
+
+// auditAction performs write action against the controller and records it
+// in the audit log together with HTTP status returned by the controller
+func auditAction(request *http.Request, action string, target string, parameters map[string]string, perform func(ctx context.Context) error) error {
+	var status client.ResponseStatus
+	err := perform(client.WithResponseStatus(request.Context(), &status))
+	recordAuditEvent(request, audit.Event{
+		Action:           action,
+		Target:           target,
+		Parameters:       parameters,
+		ControllerStatus: status.Code,
+	}, err)
+	return err
+}
+
+// recordAuditEvent records action performed by user sending the request.
+// Values of parameters are redacted the same way as in logs. Failure to
+// record the event does not change result of the action.
+func recordAuditEvent(request *http.Request, event audit.Event, err error) {
+	if auditLog == nil {
+		return
+	}
+
+	event.Parameters = logging.RedactFields(event.Parameters)
+	event.Actor = auditActor(request)
+	event.RequestID = logging.RequestID(request.Context())
+	event.Result = audit.ResultSuccess
+	if err != nil {
+		event.Result = audit.ResultFailure
+		event.Error = err.Error()
+	}
+
+	auditErr := auditLog.Record(event)
+	if auditErr != nil {
+		logging.Error(request.Context(), "Unable to record audit event", "error", auditErr)
+	}
+}
+
+// AuditDynContent represents dynamic part of HTML page with audit log.
+//
+//	Enabled: whether audit log is enabled
+//	Events: events matching the filter
+//	Actions: all actions that can be selected in the filter
+//	Actor, Action, Target, Result, From, To: filter entered by user
+type AuditDynContent struct {
+	Enabled bool
+	Events  []audit.Event
+	Actions []string
+	Actor   string
+	Action  string
+	Target  string
+	Result  string
+	From    string
+	To      string
+}
+
+// auditedActions returns sorted list of all actions recorded in audit log
+func auditedActions() []string {
+	actions := []string{
+		client.OperationCreateConfigurationProfile,
+		client.OperationChangeConfigurationProfile,
+		client.OperationDeleteConfigurationProfile,
+		client.OperationCreateClusterConfiguration,
+		client.OperationEnableClusterConfiguration,
+		client.OperationDisableClusterConfiguration,
+		client.OperationActivateTrigger,
+		client.OperationDeactivateTrigger,
+		client.OperationTriggerMustGather,
+		actionRequestMustGather,
+		actionRejectMustGather,
+		actionCancelMustGather,
+	}
+	sort.Strings(actions)
+	return actions
+}
+
+// parseAuditFilter reads filter from query parameters. The "to" date is
+// inclusive.
+func parseAuditFilter(dynData AuditDynContent) (audit.Filter, error) {
+	filter := audit.Filter{
+		Actor:  dynData.Actor,
+		Action: dynData.Action,
+		Target: dynData.Target,
+		Result: dynData.Result,
+	}
+
+	if dynData.From != "" {
+		from, err := time.Parse(auditDateFormat, dynData.From)
+		if err != nil {
+			return filter, errors.New("Invalid date in 'from' field")
+		}
+		filter.From = from
+	}
+	if dynData.To != "" {
+		to, err := time.Parse(auditDateFormat, dynData.To)
+		if err != nil {
+			return filter, errors.New("Invalid date in 'to' field")
+		}
+		filter.To = to.Add(24*time.Hour - time.Nanosecond)
+	}
+	return filter, nil
+}
+
+// auditPage displays audit log events matching the filter
+func auditPage(writer http.ResponseWriter, request *http.Request) {
+	query := request.URL.Query()
+	dynData := AuditDynContent{
+		Enabled: auditLog != nil,
+		Actions: auditedActions(),
+		Actor:   query.Get("actor"),
+		Action:  query.Get("action"),
+		Target:  query.Get("target"),
+		Result:  query.Get("result"),
+		From:    query.Get("from"),
+		To:      query.Get("to"),
+	}
+
+	if auditLog != nil {
+		filter, err := parseAuditFilter(dynData)
+		if err != nil {
+			errorPageResponse(writer, request, http.StatusBadRequest, errorPageTemplate, ErrorPageDynContent{
+				Title:     "Invalid filter",
+				Operation: "Searching audit log",
+				Message:   err.Error(),
+				RetryURL:  auditEndpoint,
+			})
+			return
+		}
+
+		dynData.Events, err = auditLog.Search(filter)
+		if err != nil {
+			logging.Error(request.Context(), "Unable to search audit log", "error", err)
+			errorPageResponse(writer, request, http.StatusInternalServerError, errorPageTemplate, ErrorPageDynContent{
+				Title:     "Unable to read audit log",
+				Operation: "Searching audit log",
+				Message:   err.Error(),
+				RetryURL:  auditEndpoint,
+			})
+			return
+		}
+	}
+
+	renderPage(writer, request, http.StatusOK, "audit.html", dynData)
+}
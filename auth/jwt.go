@@ -0,0 +1,284 @@
+/*
+Copyright © 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// allowed difference between clocks of this service and the provider
+const clockSkew = time.Minute
+
+// KeysRefreshInterval is minimal time between two reads of provider keys.
+// Tokens signed by unknown key are refused without contacting the provider
+// when the keys have been read recently.
+const KeysRefreshInterval = time.Minute
+
+// jwtHeader represents header of signed JWT
+type jwtHeader struct {
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+}
+
+// jsonWebKey represents one RSA key published by the provider
+type jsonWebKey struct {
+	KeyType string `json:"kty"`
+	KeyID   string `json:"kid"`
+	Use     string `json:"use"`
+	N       string `json:"n"`
+	E       string `json:"e"`
+}
+
+// keySet contains public keys used to verify ID tokens. Keys are read again
+// when token is signed by unknown key, i.e. after key rotation, but not more
+// often than once per KeysRefreshInterval.
+type keySet struct {
+	uri        string
+	httpClient *http.Client
+	now        func() time.Time
+
+	mutex       sync.Mutex
+	keys        map[string]*rsa.PublicKey
+	refreshedAt time.Time
+}
+
+func newKeySet(uri string, httpClient *http.Client) *keySet {
+	return &keySet{
+		uri:        uri,
+		httpClient: httpClient,
+		now:        time.Now,
+		keys:       map[string]*rsa.PublicKey{},
+	}
+}
+
+// key returns public key with given ID
+func (s *keySet) key(ctx context.Context, keyID string) (*rsa.PublicKey, error) {
+	key, found, refresh := s.lookup(keyID)
+	if found {
+		return key, nil
+	}
+	if !refresh {
+		return nil, fmt.Errorf("ID token is signed by unknown key %q", keyID)
+	}
+
+	// keys are read without the mutex locked, so verification of tokens
+	// signed by known keys is not blocked by slow provider
+	keys, err := s.read(ctx)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	s.keys = keys
+
+	key, found = s.keys[keyID]
+	if !found {
+		return nil, fmt.Errorf("ID token is signed by unknown key %q", keyID)
+	}
+	return key, nil
+}
+
+// lookup returns key with given ID if it is known. Otherwise it decides
+// whether the keys can be read from provider again.
+func (s *keySet) lookup(keyID string) (key *rsa.PublicKey, found bool, refresh bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	key, found = s.keys[keyID]
+	if found {
+		return key, true, false
+	}
+
+	now := s.now()
+	if now.Sub(s.refreshedAt) < KeysRefreshInterval {
+		return nil, false, false
+	}
+	s.refreshedAt = now
+	return nil, false, true
+}
+
+// read reads all keys from provider
+func (s *keySet) read(ctx context.Context) (map[string]*rsa.PublicKey, error) {
+	var document struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	err := getJSON(ctx, s.httpClient, s.uri, &document)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read provider keys: %v", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(document.Keys))
+	for _, jwk := range document.Keys {
+		if jwk.KeyType != "RSA" || (jwk.Use != "" && jwk.Use != "sig") {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			return nil, err
+		}
+		keys[jwk.KeyID] = key
+	}
+	return keys, nil
+}
+
+// publicKey decodes RSA public key
+func (k jsonWebKey) publicKey() (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(k.N)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid modulus of key %q: %v", k.KeyID, err)
+	}
+	e, err := base64.RawURLEncoding.DecodeString(k.E)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid exponent of key %q: %v", k.KeyID, err)
+	}
+	exponent := new(big.Int).SetBytes(e)
+	if !exponent.IsInt64() || exponent.Int64() < 3 || exponent.Int64() > 1<<31-1 {
+		return nil, fmt.Errorf("Invalid exponent of key %q", k.KeyID)
+	}
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, nil
+}
+
+// decodeSegment decodes one base64url encoded part of JWT and unmarshals it
+func decodeSegment(segment string, target interface{}) error {
+	decoded, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(decoded, target)
+}
+
+// verifyIDToken checks signature, issuer, audience, validity time and nonce
+// of ID token received on login and returns its claims
+func (p *Provider) verifyIDToken(ctx context.Context, token string, nonce string) (map[string]interface{}, error) {
+	claims, err := p.verifyToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(stringClaim(claims, "nonce")), []byte(nonce)) != 1 {
+		return nil, errors.New("ID token nonce does not match")
+	}
+	return claims, nil
+}
+
+// verifyToken checks signature, issuer, audience and validity time of ID
+// token and returns its claims. Only RS256 signatures are accepted.
+func (p *Provider) verifyToken(ctx context.Context, token string) (map[string]interface{}, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("ID token is not a signed JWT")
+	}
+
+	var header jwtHeader
+	err := decodeSegment(parts[0], &header)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid ID token header: %v", err)
+	}
+	if header.Algorithm != "RS256" {
+		return nil, fmt.Errorf("Unsupported ID token signature algorithm %q", header.Algorithm)
+	}
+
+	key, err := p.keys.key(ctx, header.KeyID)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("Invalid ID token signature: %v", err)
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	err = rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature)
+	if err != nil {
+		return nil, errors.New("ID token signature is not valid")
+	}
+
+	var claims map[string]interface{}
+	err = decodeSegment(parts[1], &claims)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid ID token claims: %v", err)
+	}
+
+	err = p.checkClaims(claims, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// checkClaims checks standard claims of ID token
+func (p *Provider) checkClaims(claims map[string]interface{}, now time.Time) error {
+	if stringClaim(claims, "iss") != p.metadata.Issuer {
+		return fmt.Errorf("ID token issued by unexpected issuer %q", stringClaim(claims, "iss"))
+	}
+	if !hasAudience(claims["aud"], p.configuration.ClientID) {
+		return errors.New("ID token is not issued for this client")
+	}
+
+	return checkValidity(claims, now)
+}
+
+// checkValidity checks that ID token is valid at given time, difference of
+// clocks up to clockSkew is tolerated
+func checkValidity(claims map[string]interface{}, now time.Time) error {
+	expiration, ok := timeClaim(claims, "exp")
+	if !ok || now.After(expiration.Add(clockSkew)) {
+		return errors.New("ID token has expired")
+	}
+	issuedAt, ok := timeClaim(claims, "iat")
+	if !ok || now.Add(clockSkew).Before(issuedAt) {
+		return errors.New("ID token issue time is missing or in the future")
+	}
+	notBefore, ok := timeClaim(claims, "nbf")
+	if ok && now.Add(clockSkew).Before(notBefore) {
+		return errors.New("ID token is not valid yet")
+	}
+	return nil
+}
+
+// timeClaim returns value of claim with number of seconds since epoch
+func timeClaim(claims map[string]interface{}, name string) (time.Time, bool) {
+	seconds, ok := claims[name].(float64)
+	return time.Unix(int64(seconds), 0), ok
+}
+
+// hasAudience checks the aud claim, it can be either string or list
+func hasAudience(audience interface{}, clientID string) bool {
+	switch value := audience.(type) {
+	case string:
+		return value == clientID
+	case []interface{}:
+		for _, item := range value {
+			if item == clientID {
+				return true
+			}
+		}
+	}
+	return false
+}
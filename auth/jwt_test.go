@@ -0,0 +1,456 @@
+/*
+Copyright © 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const (
+	testIssuer   = "https://sso.example.com/realms/test"
+	testClientID = "insights-web-ui"
+	testNonce    = "nonce-1234"
+	testKeyID    = "key-1"
+)
+
+// testProvider serves keys of the provider and counts how many times they
+// have been read
+type testProvider struct {
+	key      *rsa.PrivateKey
+	keyID    string
+	requests int32
+	server   *httptest.Server
+	provider *Provider
+}
+
+func newTestProvider(t *testing.T) *testProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &testProvider{key: key, keyID: testKeyID}
+
+	p.server = httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		atomic.AddInt32(&p.requests, 1)
+		jwk := jsonWebKey{
+			KeyType: "RSA",
+			KeyID:   p.keyID,
+			Use:     "sig",
+			N:       base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E:       base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}
+		err := json.NewEncoder(writer).Encode(map[string][]jsonWebKey{"keys": {jwk}})
+		if err != nil {
+			t.Error(err)
+		}
+	}))
+	t.Cleanup(p.server.Close)
+
+	p.provider = &Provider{
+		configuration: Configuration{IssuerURL: testIssuer, ClientID: testClientID},
+		metadata:      providerMetadata{Issuer: testIssuer, JWKSURI: p.server.URL},
+		keys:          newKeySet(p.server.URL, p.server.Client()),
+		httpClient:    p.server.Client(),
+	}
+	return p
+}
+
+// validClaims returns claims of ID token accepted by the test provider
+func validClaims() map[string]interface{} {
+	return map[string]interface{}{
+		"iss":   testIssuer,
+		"aud":   testClientID,
+		"sub":   "1234",
+		"iat":   time.Now().Unix(),
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"nonce": testNonce,
+	}
+}
+
+// claimsWith returns valid claims with given claim changed, nil value removes
+// the claim
+func claimsWith(name string, value interface{}) map[string]interface{} {
+	claims := validClaims()
+	if value == nil {
+		delete(claims, name)
+	} else {
+		claims[name] = value
+	}
+	return claims
+}
+
+// encodeSegment encodes one part of JWT
+func encodeSegment(t *testing.T, value interface{}) string {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(encoded)
+}
+
+// sign constructs JWT with given header and claims signed by the key
+func sign(t *testing.T, key *rsa.PrivateKey, header jwtHeader, claims map[string]interface{}) string {
+	signed := encodeSegment(t, header) + "." + encodeSegment(t, claims)
+	digest := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+// TestVerifyIDToken checks that only tokens signed by provider key with
+// expected claims are accepted
+func TestVerifyIDToken(t *testing.T) {
+	p := newTestProvider(t)
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rs256 := jwtHeader{Algorithm: "RS256", KeyID: testKeyID}
+
+	tests := []struct {
+		name   string
+		token  func() string
+		nonce  string
+		errMsg string
+	}{
+		{
+			name:  "valid token",
+			token: func() string { return sign(t, p.key, rs256, validClaims()) },
+		},
+		{
+			name: "audience in list",
+			token: func() string {
+				claims := validClaims()
+				claims["aud"] = []string{"other-client", testClientID}
+				return sign(t, p.key, rs256, claims)
+			},
+		},
+		{
+			name:  "expired within clock skew",
+			token: func() string { return sign(t, p.key, rs256, claimsWith("exp", time.Now().Add(-clockSkew/2).Unix())) },
+		},
+		{
+			name:  "issued within clock skew",
+			token: func() string { return sign(t, p.key, rs256, claimsWith("iat", time.Now().Add(clockSkew/2).Unix())) },
+		},
+		{
+			name:  "valid within clock skew",
+			token: func() string { return sign(t, p.key, rs256, claimsWith("nbf", time.Now().Add(clockSkew/2).Unix())) },
+		},
+		{
+			name:  "already valid",
+			token: func() string { return sign(t, p.key, rs256, claimsWith("nbf", time.Now().Add(-time.Minute).Unix())) },
+		},
+		{
+			name:   "not a JWT",
+			token:  func() string { return "not-a-token" },
+			errMsg: "not a signed JWT",
+		},
+		{
+			name: "unsigned token",
+			token: func() string {
+				header := jwtHeader{Algorithm: "none", KeyID: testKeyID}
+				return encodeSegment(t, header) + "." + encodeSegment(t, validClaims()) + "."
+			},
+			errMsg: "Unsupported ID token signature algorithm",
+		},
+		{
+			name: "HMAC signature",
+			token: func() string {
+				header := jwtHeader{Algorithm: "HS256", KeyID: testKeyID}
+				return sign(t, p.key, header, validClaims())
+			},
+			errMsg: "Unsupported ID token signature algorithm",
+		},
+		{
+			name:   "signed by other key",
+			token:  func() string { return sign(t, otherKey, rs256, validClaims()) },
+			errMsg: "signature is not valid",
+		},
+		{
+			name: "modified claims",
+			token: func() string {
+				parts := strings.Split(sign(t, p.key, rs256, validClaims()), ".")
+				claims := validClaims()
+				claims["sub"] = "admin"
+				return parts[0] + "." + encodeSegment(t, claims) + "." + parts[2]
+			},
+			errMsg: "signature is not valid",
+		},
+		{
+			name: "unknown key",
+			token: func() string {
+				header := jwtHeader{Algorithm: "RS256", KeyID: "key-2"}
+				return sign(t, otherKey, header, validClaims())
+			},
+			errMsg: "signed by unknown key",
+		},
+		{
+			name: "unknown key ID",
+			token: func() string {
+				header := jwtHeader{Algorithm: "RS256", KeyID: "key-2"}
+				return sign(t, p.key, header, validClaims())
+			},
+			errMsg: "signed by unknown key",
+		},
+		{
+			name:   "missing key ID",
+			token:  func() string { return sign(t, p.key, jwtHeader{Algorithm: "RS256"}, validClaims()) },
+			errMsg: "signed by unknown key",
+		},
+		{
+			name: "wrong issuer",
+			token: func() string {
+				claims := validClaims()
+				claims["iss"] = "https://evil.example.com"
+				return sign(t, p.key, rs256, claims)
+			},
+			errMsg: "unexpected issuer",
+		},
+		{
+			name: "wrong audience",
+			token: func() string {
+				claims := validClaims()
+				claims["aud"] = "other-client"
+				return sign(t, p.key, rs256, claims)
+			},
+			errMsg: "not issued for this client",
+		},
+		{
+			name: "audience list without the client",
+			token: func() string {
+				return sign(t, p.key, rs256, claimsWith("aud", []string{"other-client", "third-client"}))
+			},
+			errMsg: "not issued for this client",
+		},
+		{
+			name:   "audience list in wrong format",
+			token:  func() string { return sign(t, p.key, rs256, claimsWith("aud", []int{1, 2})) },
+			errMsg: "not issued for this client",
+		},
+		{
+			name: "missing audience",
+			token: func() string {
+				claims := validClaims()
+				delete(claims, "aud")
+				return sign(t, p.key, rs256, claims)
+			},
+			errMsg: "not issued for this client",
+		},
+		{
+			name: "expired token",
+			token: func() string {
+				claims := validClaims()
+				claims["exp"] = time.Now().Add(-clockSkew - time.Minute).Unix()
+				return sign(t, p.key, rs256, claims)
+			},
+			errMsg: "has expired",
+		},
+		{
+			name: "missing expiration",
+			token: func() string {
+				claims := validClaims()
+				delete(claims, "exp")
+				return sign(t, p.key, rs256, claims)
+			},
+			errMsg: "has expired",
+		},
+		{
+			name:   "expiration in wrong format",
+			token:  func() string { return sign(t, p.key, rs256, claimsWith("exp", "never")) },
+			errMsg: "has expired",
+		},
+		{
+			name: "issued in the future",
+			token: func() string {
+				return sign(t, p.key, rs256, claimsWith("iat", time.Now().Add(clockSkew+time.Minute).Unix()))
+			},
+			errMsg: "issue time is missing or in the future",
+		},
+		{
+			name:   "missing issue time",
+			token:  func() string { return sign(t, p.key, rs256, claimsWith("iat", nil)) },
+			errMsg: "issue time is missing or in the future",
+		},
+		{
+			name: "not valid yet",
+			token: func() string {
+				return sign(t, p.key, rs256, claimsWith("nbf", time.Now().Add(clockSkew+time.Minute).Unix()))
+			},
+			errMsg: "not valid yet",
+		},
+		{
+			name:   "nonce mismatch",
+			token:  func() string { return sign(t, p.key, rs256, validClaims()) },
+			nonce:  "other-nonce",
+			errMsg: "nonce does not match",
+		},
+		{
+			name: "missing nonce",
+			token: func() string {
+				claims := validClaims()
+				delete(claims, "nonce")
+				return sign(t, p.key, rs256, claims)
+			},
+			errMsg: "nonce does not match",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nonce := tt.nonce
+			if nonce == "" {
+				nonce = testNonce
+			}
+			claims, err := p.provider.verifyIDToken(context.Background(), tt.token(), nonce)
+			if tt.errMsg == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if stringClaim(claims, "sub") != "1234" {
+					t.Errorf("unexpected claims %v", claims)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("expected error containing %q, got %v", tt.errMsg, err)
+			}
+		})
+	}
+}
+
+// TestNonceReplay checks that ID token is accepted only by the login it has
+// been issued for and that the login can't be finished again
+func TestNonceReplay(t *testing.T) {
+	p := newTestProvider(t)
+	store := NewSessionStore(0)
+	ctx := context.Background()
+
+	state, nonce, err := store.StartLogin("/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := sign(t, p.key, jwtHeader{Algorithm: "RS256", KeyID: testKeyID}, claimsWith("nonce", nonce))
+
+	finishedNonce, _, ok := store.FinishLogin(state)
+	if !ok {
+		t.Fatal("login not finished")
+	}
+	_, err = p.provider.verifyIDToken(ctx, token, finishedNonce)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, _, ok = store.FinishLogin(state)
+	if ok {
+		t.Fatal("login finished twice")
+	}
+
+	// the same token sent to another login
+	state, _, err = store.StartLogin("/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherNonce, _, _ := store.FinishLogin(state)
+	_, err = p.provider.verifyIDToken(ctx, token, otherNonce)
+	if err == nil || !strings.Contains(err.Error(), "nonce does not match") {
+		t.Errorf("token accepted by another login: %v", err)
+	}
+}
+
+// TestVerifyBearerToken checks that tokens sent by API clients don't need
+// nonce, but other claims are checked
+func TestVerifyBearerToken(t *testing.T) {
+	p := newTestProvider(t)
+	p.provider.configuration.UsernameClaim = DefaultUsernameClaim
+	rs256 := jwtHeader{Algorithm: "RS256", KeyID: testKeyID}
+	ctx := context.Background()
+
+	claims := claimsWith("nonce", nil)
+	claims[DefaultUsernameClaim] = "jdoe"
+	identity, err := p.provider.VerifyBearerToken(ctx, sign(t, p.key, rs256, claims))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if identity.Username != "jdoe" || identity.Subject != "1234" {
+		t.Errorf("unexpected identity %v", identity)
+	}
+
+	claims["exp"] = time.Now().Add(-clockSkew - time.Minute).Unix()
+	_, err = p.provider.VerifyBearerToken(ctx, sign(t, p.key, rs256, claims))
+	if err == nil {
+		t.Error("expired token accepted")
+	}
+}
+
+// TestKeysRefresh checks that keys are read again after key rotation, but
+// not more often than once per refresh interval
+func TestKeysRefresh(t *testing.T) {
+	p := newTestProvider(t)
+	now := time.Now()
+	p.provider.keys.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	_, err := p.provider.keys.key(ctx, testKeyID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = p.provider.keys.key(ctx, testKeyID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if requests := atomic.LoadInt32(&p.requests); requests != 1 {
+		t.Fatalf("known key should be cached, provider read %d times", requests)
+	}
+
+	// unknown keys don't cause more reads within the interval
+	for i := 0; i < 10; i++ {
+		_, err = p.provider.keys.key(ctx, "key-2")
+		if err == nil {
+			t.Fatal("unknown key should be refused")
+		}
+	}
+	if requests := atomic.LoadInt32(&p.requests); requests != 1 {
+		t.Fatalf("keys read recently, provider read %d times", requests)
+	}
+
+	// provider rotated the key
+	p.keyID = "key-2"
+	now = now.Add(KeysRefreshInterval)
+	_, err = p.provider.keys.key(ctx, "key-2")
+	if err != nil {
+		t.Fatalf("rotated key should be read: %v", err)
+	}
+	if requests := atomic.LoadInt32(&p.requests); requests != 2 {
+		t.Fatalf("expected second read of keys, provider read %d times", requests)
+	}
+}
@@ -0,0 +1,275 @@
+/*
+Copyright © 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package auth implements login of web UI users via OpenID Connect provider
+// (authorization code flow) and sessions of logged in users.
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/tisnik/insights-operator-web-ui/logging"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultUsernameClaim is ID token claim used as user name when no other
+// claim is configured
+const DefaultUsernameClaim = "preferred_username"
+
+// DefaultGroupsClaim is ID token claim with list of groups the user belongs
+// to when no other claim is configured
+const DefaultGroupsClaim = "groups"
+
+// ProviderTimeout is timeout for calls made to the OpenID Connect provider
+const ProviderTimeout = 10 * time.Second
+
+// Configuration represents configuration of the OpenID Connect provider.
+//
+//	IssuerURL: URL of the provider, used to discover its endpoints
+//	ClientID: ID of this application registered at the provider
+//	ClientSecret: secret of this application registered at the provider
+//	RedirectURL: URL of the callback endpoint the provider redirects to
+//	Scopes: scopes requested in addition to "openid"
+//	UsernameClaim: ID token claim used as user name
+//	GroupsClaim: ID token claim with groups the user belongs to
+type Configuration struct {
+	IssuerURL     string
+	ClientID      string
+	ClientSecret  string
+	RedirectURL   string
+	Scopes        []string
+	UsernameClaim string
+	GroupsClaim   string
+}
+
+// Identity represents the logged in user.
+//
+//	Subject: unique ID of user at the provider
+//	Username: user name used as author of changes made in the UI
+//	Email: e-mail address, if provided
+//	Groups: groups the user belongs to, if provided
+type Identity struct {
+	Subject  string
+	Username string
+	Email    string
+	Groups   []string
+}
+
+// providerMetadata contains endpoints published by the provider in its
+// discovery document
+type providerMetadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// tokenResponse is returned by the provider's token endpoint
+type tokenResponse struct {
+	IDToken string `json:"id_token"`
+}
+
+// Provider performs login via OpenID Connect provider.
+type Provider struct {
+	configuration Configuration
+	metadata      providerMetadata
+	keys          *keySet
+	httpClient    *http.Client
+}
+
+// NewProvider reads discovery document of the provider. An error is returned
+// when the provider can't be contacted or when the document is not valid.
+func NewProvider(ctx context.Context, configuration Configuration) (*Provider, error) {
+	if configuration.UsernameClaim == "" {
+		configuration.UsernameClaim = DefaultUsernameClaim
+	}
+	if configuration.GroupsClaim == "" {
+		configuration.GroupsClaim = DefaultGroupsClaim
+	}
+	httpClient := &http.Client{Timeout: ProviderTimeout}
+
+	discoveryURL := strings.TrimSuffix(configuration.IssuerURL, "/") + "/.well-known/openid-configuration"
+	var metadata providerMetadata
+	err := getJSON(ctx, httpClient, discoveryURL, &metadata)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read OpenID Connect discovery document: %v", err)
+	}
+
+	if metadata.Issuer != configuration.IssuerURL {
+		return nil, fmt.Errorf("Issuer %s announced by the provider does not match configured issuer %s", metadata.Issuer, configuration.IssuerURL)
+	}
+	if metadata.AuthorizationEndpoint == "" || metadata.TokenEndpoint == "" || metadata.JWKSURI == "" {
+		return nil, fmt.Errorf("OpenID Connect discovery document of %s is not complete", configuration.IssuerURL)
+	}
+
+	logging.Info(ctx, "Using OpenID Connect provider", "issuer", metadata.Issuer)
+	return &Provider{
+		configuration: configuration,
+		metadata:      metadata,
+		keys:          newKeySet(metadata.JWKSURI, httpClient),
+		httpClient:    httpClient,
+	}, nil
+}
+
+// AuthCodeURL returns URL of the provider's login page. State and nonce are
+// checked when the user is redirected back.
+func (p *Provider) AuthCodeURL(state string, nonce string) string {
+	scopes := append([]string{"openid"}, p.configuration.Scopes...)
+	query := url.Values{
+		"response_type": {"code"},
+		"client_id":     {p.configuration.ClientID},
+		"redirect_uri":  {p.configuration.RedirectURL},
+		"scope":         {strings.Join(scopes, " ")},
+		"state":         {state},
+		"nonce":         {nonce},
+	}
+
+	separator := "?"
+	if strings.Contains(p.metadata.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return p.metadata.AuthorizationEndpoint + separator + query.Encode()
+}
+
+// Exchange exchanges authorization code for ID token, verifies the token
+// and returns identity of the logged in user
+func (p *Provider) Exchange(ctx context.Context, code string, nonce string) (*Identity, error) {
+	idToken, err := p.requestIDToken(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, err := p.verifyIDToken(ctx, idToken, nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.identity(claims)
+}
+
+// VerifyBearerToken verifies ID token sent by API client in Authorization
+// header and returns identity of the user. The token needs to be issued by
+// the provider for the configured client, its nonce is not checked, because
+// the login has not been started by the web UI.
+func (p *Provider) VerifyBearerToken(ctx context.Context, token string) (*Identity, error) {
+	claims, err := p.verifyToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.identity(claims)
+}
+
+// requestIDToken calls the token endpoint
+func (p *Provider) requestIDToken(ctx context.Context, code string) (string, error) {
+	form := url.Values{
+		"grant_type":   {"authorization_code"},
+		"code":         {code},
+		"redirect_uri": {p.configuration.RedirectURL},
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, ProviderTimeout)
+	defer cancel()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, p.metadata.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.SetBasicAuth(url.QueryEscape(p.configuration.ClientID), url.QueryEscape(p.configuration.ClientSecret))
+
+	var token tokenResponse
+	err = doJSON(p.httpClient, request, &token)
+	if err != nil {
+		return "", fmt.Errorf("Unable to exchange authorization code: %v", err)
+	}
+	if token.IDToken == "" {
+		return "", fmt.Errorf("Provider did not return ID token")
+	}
+	return token.IDToken, nil
+}
+
+// identity constructs identity from verified ID token claims
+func (p *Provider) identity(claims map[string]interface{}) (*Identity, error) {
+	identity := &Identity{
+		Subject:  stringClaim(claims, "sub"),
+		Username: stringClaim(claims, p.configuration.UsernameClaim),
+		Email:    stringClaim(claims, "email"),
+		Groups:   stringListClaim(claims, p.configuration.GroupsClaim),
+	}
+	if identity.Subject == "" {
+		return nil, fmt.Errorf("ID token does not contain subject")
+	}
+	if identity.Username == "" {
+		return nil, fmt.Errorf("ID token does not contain claim %s with user name", p.configuration.UsernameClaim)
+	}
+	return identity, nil
+}
+
+// stringClaim returns claim value if it is a string, empty string otherwise
+func stringClaim(claims map[string]interface{}, name string) string {
+	value, _ := claims[name].(string)
+	return value
+}
+
+// stringListClaim returns all strings from claim that is a list
+func stringListClaim(claims map[string]interface{}, name string) []string {
+	values, _ := claims[name].([]interface{})
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		if item, ok := value.(string); ok {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// getJSON reads JSON document from given URL
+func getJSON(ctx context.Context, httpClient *http.Client, url string, target interface{}) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	return doJSON(httpClient, request, target)
+}
+
+// doJSON performs request and unmarshals JSON response
+func doJSON(httpClient *http.Client, request *http.Request, target interface{}) error {
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err := response.Body.Close()
+		if err != nil {
+			logging.Warn(request.Context(), "Unable to close response body", "error", err)
+		}
+	}()
+
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return err
+	}
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned HTTP status %d: %s", request.URL.Redacted(), response.StatusCode, body)
+	}
+	return json.Unmarshal(body, target)
+}
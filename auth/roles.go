@@ -0,0 +1,143 @@
+/*
+Copyright © 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+)
+
+// Role represents set of permissions given to user
+type Role string
+
+// Roles that can be assigned to users
+const (
+	// RoleViewer can read clusters, profiles, configurations and triggers
+	RoleViewer Role = "viewer"
+
+	// RoleEditor can also create profiles and configurations, enable and
+	// disable configurations, activate and deactivate triggers and request
+	// must-gather
+	RoleEditor Role = "editor"
+
+	// RoleMustGatherApprover can read everything, request must-gather and
+	// approve must-gather requested by other users
+	RoleMustGatherApprover Role = "must_gather_approver"
+
+	// RoleAdmin can perform all operations and read audit log
+	RoleAdmin Role = "admin"
+)
+
+// Permission represents kind of operation performed in the UI
+type Permission string
+
+// Permissions checked for endpoints
+const (
+	PermissionRead              Permission = "read"
+	PermissionWrite             Permission = "write"
+	PermissionMustGather        Permission = "must_gather"
+	PermissionApproveMustGather Permission = "approve_must_gather"
+	PermissionAudit             Permission = "audit"
+)
+
+// permissions given to individual roles
+var rolePermissions = map[Role][]Permission{
+	RoleViewer:             {PermissionRead},
+	RoleEditor:             {PermissionRead, PermissionWrite, PermissionMustGather},
+	RoleMustGatherApprover: {PermissionRead, PermissionMustGather, PermissionApproveMustGather},
+	RoleAdmin:              {PermissionRead, PermissionWrite, PermissionMustGather, PermissionApproveMustGather, PermissionAudit},
+}
+
+// Policy maps users and groups they belong to to roles.
+//
+//	DefaultRole: role given to all logged in users, can be empty
+//	Groups: roles of members of groups
+//	Users: roles of individual users
+type Policy struct {
+	DefaultRole Role              `json:"default_role"`
+	Groups      map[string][]Role `json:"groups"`
+	Users       map[string][]Role `json:"users"`
+}
+
+// LoadPolicyFile reads policy from JSON file
+func LoadPolicyFile(filename string) (*Policy, error) {
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read policy file %s: %v", filename, err)
+	}
+
+	var policy Policy
+	err = json.Unmarshal(content, &policy)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to parse policy file %s: %v", filename, err)
+	}
+
+	err = policy.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("Invalid policy file %s: %v", filename, err)
+	}
+	return &policy, nil
+}
+
+// Validate checks that the policy refers to known roles only
+func (p *Policy) Validate() error {
+	if p.DefaultRole != "" && !p.DefaultRole.valid() {
+		return fmt.Errorf("unknown default role %q", p.DefaultRole)
+	}
+	for _, mapping := range []map[string][]Role{p.Groups, p.Users} {
+		for name, roles := range mapping {
+			for _, role := range roles {
+				if !role.valid() {
+					return fmt.Errorf("unknown role %q assigned to %s", role, name)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// valid checks whether the role is known
+func (r Role) valid() bool {
+	_, found := rolePermissions[r]
+	return found
+}
+
+// RolesFor returns all roles of given user
+func (p *Policy) RolesFor(identity Identity) []Role {
+	var roles []Role
+	if p.DefaultRole != "" {
+		roles = append(roles, p.DefaultRole)
+	}
+	roles = append(roles, p.Users[identity.Username]...)
+	for _, group := range identity.Groups {
+		roles = append(roles, p.Groups[group]...)
+	}
+	return roles
+}
+
+// HasPermission checks whether any of roles gives the permission
+func HasPermission(roles []Role, permission Permission) bool {
+	for _, role := range roles {
+		for _, granted := range rolePermissions[role] {
+			if granted == permission {
+				return true
+			}
+		}
+	}
+	return false
+}
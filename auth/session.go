@@ -0,0 +1,208 @@
+/*
+Copyright © 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"sync"
+	"time"
+)
+
+// DefaultSessionLifetime is used when no session lifetime is configured
+const DefaultSessionLifetime = 8 * time.Hour
+
+// LoginTimeout is time given to the user to log in at the provider
+const LoginTimeout = 10 * time.Minute
+
+// MaxPendingLogins is maximum number of logins that are started, but not
+// finished yet. The oldest login is forgotten when a new one is started and
+// the limit is reached, so the login page can't be used to exhaust memory.
+const MaxPendingLogins = 1000
+
+// Session represents logged in user.
+//
+//	ID: random ID of session sent in cookie
+//	Identity: the logged in user
+//	Roles: roles of the user, assigned on login
+//	CSRFToken: token that needs to be sent with all forms
+//	ExpiresAt: time when the user needs to log in again
+type Session struct {
+	ID        string
+	Identity  Identity
+	Roles     []Role
+	CSRFToken string
+	ExpiresAt time.Time
+}
+
+// pendingLogin represents login started by user, but not finished yet
+type pendingLogin struct {
+	nonce     string
+	returnTo  string
+	expiresAt time.Time
+}
+
+// SessionStore keeps sessions of logged in users in memory.
+type SessionStore struct {
+	lifetime time.Duration
+
+	mutex    sync.Mutex
+	sessions map[string]*Session
+	pending  map[string]pendingLogin
+}
+
+// NewSessionStore constructs empty session store
+func NewSessionStore(lifetime time.Duration) *SessionStore {
+	if lifetime <= 0 {
+		lifetime = DefaultSessionLifetime
+	}
+	return &SessionStore{
+		lifetime: lifetime,
+		sessions: map[string]*Session{},
+		pending:  map[string]pendingLogin{},
+	}
+}
+
+// RandomToken generates random URL-safe string that can be used as session
+// ID or CSRF token
+func RandomToken() (string, error) {
+	buffer := make([]byte, 32)
+	_, err := rand.Read(buffer)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buffer), nil
+}
+
+// removeExpired removes expired sessions and logins, must be called with
+// mutex locked
+func (s *SessionStore) removeExpired(now time.Time) {
+	for id, session := range s.sessions {
+		if now.After(session.ExpiresAt) {
+			delete(s.sessions, id)
+		}
+	}
+	for state, login := range s.pending {
+		if now.After(login.expiresAt) {
+			delete(s.pending, state)
+		}
+	}
+}
+
+// removeOldestLogin removes pending login that expires first, must be called
+// with mutex locked
+func (s *SessionStore) removeOldestLogin() {
+	oldestState := ""
+	var oldest time.Time
+	for state, login := range s.pending {
+		if oldestState == "" || login.expiresAt.Before(oldest) {
+			oldestState = state
+			oldest = login.expiresAt
+		}
+	}
+	delete(s.pending, oldestState)
+}
+
+// StartLogin generates state and nonce for a new login. URL the user will be
+// redirected to after login is remembered.
+func (s *SessionStore) StartLogin(returnTo string) (state string, nonce string, err error) {
+	state, err = RandomToken()
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err = RandomToken()
+	if err != nil {
+		return "", "", err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	now := time.Now()
+	s.removeExpired(now)
+	if len(s.pending) >= MaxPendingLogins {
+		s.removeOldestLogin()
+	}
+	s.pending[state] = pendingLogin{
+		nonce:     nonce,
+		returnTo:  returnTo,
+		expiresAt: now.Add(LoginTimeout),
+	}
+	return state, nonce, nil
+}
+
+// FinishLogin returns nonce and return URL for login with given state. Each
+// state can be used only once.
+func (s *SessionStore) FinishLogin(state string) (nonce string, returnTo string, ok bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	login, found := s.pending[state]
+	if !found || time.Now().After(login.expiresAt) {
+		return "", "", false
+	}
+	delete(s.pending, state)
+	return login.nonce, login.returnTo, true
+}
+
+// Create creates new session for given user with given roles
+func (s *SessionStore) Create(identity Identity, roles []Role) (*Session, error) {
+	id, err := RandomToken()
+	if err != nil {
+		return nil, err
+	}
+	csrfToken, err := RandomToken()
+	if err != nil {
+		return nil, err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	now := time.Now()
+	s.removeExpired(now)
+	session := &Session{
+		ID:        id,
+		Identity:  identity,
+		Roles:     roles,
+		CSRFToken: csrfToken,
+		ExpiresAt: now.Add(s.lifetime),
+	}
+	s.sessions[id] = session
+	return session, nil
+}
+
+// Get returns session with given ID if it exists and has not expired yet
+func (s *SessionStore) Get(id string) (*Session, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	session, found := s.sessions[id]
+	if !found {
+		return nil, false
+	}
+	if time.Now().After(session.ExpiresAt) {
+		delete(s.sessions, id)
+		return nil, false
+	}
+	return session, true
+}
+
+// Delete removes session with given ID, i.e. logs the user out
+func (s *SessionStore) Delete(id string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.sessions, id)
+}
@@ -0,0 +1,74 @@
+/*
+Copyright © 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package auth
+
+import (
+	"testing"
+)
+
+// TestFinishLogin checks that each login can be finished only once
+func TestFinishLogin(t *testing.T) {
+	store := NewSessionStore(0)
+
+	state, nonce, err := store.StartLogin("/list-clusters")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	finishedNonce, returnTo, ok := store.FinishLogin(state)
+	if !ok || finishedNonce != nonce || returnTo != "/list-clusters" {
+		t.Fatalf("unexpected login %q %q %v", finishedNonce, returnTo, ok)
+	}
+	_, _, ok = store.FinishLogin(state)
+	if ok {
+		t.Fatal("login finished twice")
+	}
+	_, _, ok = store.FinishLogin("unknown")
+	if ok {
+		t.Fatal("unknown login finished")
+	}
+}
+
+// TestPendingLoginsLimit checks that number of started logins is limited
+// and the oldest ones are forgotten
+func TestPendingLoginsLimit(t *testing.T) {
+	store := NewSessionStore(0)
+
+	first, _, err := store.StartLogin("/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var last string
+	for i := 0; i < MaxPendingLogins; i++ {
+		last, _, err = store.StartLogin("/")
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(store.pending) != MaxPendingLogins {
+		t.Errorf("expected %d pending logins, got %d", MaxPendingLogins, len(store.pending))
+	}
+	_, _, ok := store.FinishLogin(first)
+	if ok {
+		t.Error("the oldest login should be forgotten")
+	}
+	_, _, ok = store.FinishLogin(last)
+	if !ok {
+		t.Error("the newest login should be kept")
+	}
+}
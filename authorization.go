@@ -0,0 +1,144 @@
+/*
+Copyright © 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"github.com/tisnik/insights-operator-web-ui/auth"
+	"github.com/tisnik/insights-operator-web-ui/logging"
+	"net/http"
+)
+
+// AuthorizationConfiguration represents mapping of users to roles. Roles are
+// read either from policy file or from lists of groups.
+//
+//	Enabled: permissions are checked for all endpoints when enabled
+//	PolicyFile: JSON file with policy, groups below are ignored when set
+//	DefaultRole: role given to all logged in users, can be empty
+//	ViewerGroups: groups whose members have the viewer role
+//	EditorGroups: groups whose members have the editor role
+//	MustGatherApproverGroups: groups whose members can approve must-gather
+//	AdminGroups: groups whose members have the admin role
+type AuthorizationConfiguration struct {
+	Enabled                  bool
+	PolicyFile               string
+	DefaultRole              string
+	ViewerGroups             []string
+	EditorGroups             []string
+	MustGatherApproverGroups []string
+	AdminGroups              []string
+}
+
+// descriptions of permissions displayed when access is denied
+var permissionDescriptions = map[auth.Permission]string{
+	auth.PermissionRead:              "read data from the controller",
+	auth.PermissionWrite:             "change profiles, configurations and triggers",
+	auth.PermissionMustGather:        "request must-gather",
+	auth.PermissionApproveMustGather: "approve must-gather",
+	auth.PermissionAudit:             "read audit log",
+}
+
+// policy maps users to roles, it is nil when authorization is disabled
+var policy *auth.Policy
+
+// newPolicy constructs policy from policy file or from lists of groups
+func newPolicy(configuration AuthorizationConfiguration) (*auth.Policy, error) {
+	if configuration.PolicyFile != "" {
+		return auth.LoadPolicyFile(configuration.PolicyFile)
+	}
+
+	groups := map[string][]auth.Role{}
+	for role, members := range map[auth.Role][]string{
+		auth.RoleViewer:             configuration.ViewerGroups,
+		auth.RoleEditor:             configuration.EditorGroups,
+		auth.RoleMustGatherApprover: configuration.MustGatherApproverGroups,
+		auth.RoleAdmin:              configuration.AdminGroups,
+	} {
+		for _, group := range members {
+			groups[group] = append(groups[group], role)
+		}
+	}
+
+	p := &auth.Policy{
+		DefaultRole: auth.Role(configuration.DefaultRole),
+		Groups:      groups,
+	}
+	err := p.Validate()
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// initAuthorization constructs the policy when authorization is enabled.
+// Roles are assigned to logged in users, so login needs to be enabled too.
+func initAuthorization(configuration AuthorizationConfiguration) error {
+	if !configuration.Enabled {
+		logging.Info(context.Background(), "Authorization is disabled, all users can perform all operations")
+		return nil
+	}
+	if !loginEnabled() {
+		return errors.New("Authorization requires user login to be enabled")
+	}
+
+	p, err := newPolicy(configuration)
+	if err != nil {
+		return err
+	}
+	policy = p
+	return nil
+}
+
+// rolesFor returns roles of the user, no roles are given when authorization
+// is disabled
+func rolesFor(identity auth.Identity) []auth.Role {
+	if policy == nil {
+		return nil
+	}
+	return policy.RolesFor(identity)
+}
+
+// allowed checks whether the user sending the request has given permission
+func allowed(request *http.Request, permission auth.Permission) bool {
+	if policy == nil {
+		return true
+	}
+	session := currentSession(request)
+	return session != nil && auth.HasPermission(session.Roles, permission)
+}
+
+// requirePermission returns middleware that denies access to users without
+// given permission
+func requirePermission(permission auth.Permission) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			if allowed(request, permission) {
+				next.ServeHTTP(writer, request)
+				return
+			}
+
+			logging.Warn(request.Context(), "Access denied", "username", currentUsername(request), "permission", permission)
+			errorPageResponse(writer, request, http.StatusForbidden, errorPageTemplate, ErrorPageDynContent{
+				Title:     "Access denied",
+				Operation: request.Method + " " + request.URL.Path,
+				Message:   "You are not allowed to " + permissionDescriptions[permission],
+				RetryURL:  "/",
+			})
+		})
+	}
+}
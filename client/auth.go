@@ -0,0 +1,164 @@
+/*
+Copyright © 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"errors"
+	"fmt"
+	"github.com/tisnik/insights-operator-web-ui/logging"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AuthConfiguration represents credentials used to authenticate the client
+// against the controller (or the auth proxy in front of it). At most one
+// authentication method can be configured.
+//
+//	Token: static bearer token
+//	TokenFile: file with bearer token, re-read when the file changes
+//	Username: user name for basic authentication
+//	Password: password for basic authentication
+type AuthConfiguration struct {
+	Token     string
+	TokenFile string
+	Username  string
+	Password  string
+}
+
+// authenticator adds credentials to requests sent to the controller
+type authenticator interface {
+	authenticate(request *http.Request)
+}
+
+// noAuth is used when no credentials are configured
+type noAuth struct{}
+
+func (noAuth) authenticate(*http.Request) {
+}
+
+// staticTokenAuth sends the same bearer token with every request
+type staticTokenAuth struct {
+	token string
+}
+
+func (a staticTokenAuth) authenticate(request *http.Request) {
+	request.Header.Set("Authorization", "Bearer "+a.token)
+}
+
+// basicAuth sends user name and password with every request
+type basicAuth struct {
+	username string
+	password string
+}
+
+func (a basicAuth) authenticate(request *http.Request) {
+	request.SetBasicAuth(a.username, a.password)
+}
+
+// tokenFileAuth reads bearer token from file. The file is read again when
+// it changes on disk, so rotated tokens (for example projected service
+// account tokens) are used without restarting the service.
+type tokenFileAuth struct {
+	filename string
+
+	mutex    sync.RWMutex
+	token    string
+	loadedAt time.Time
+}
+
+// newTokenFileAuth reads the token from given file
+func newTokenFileAuth(filename string) (*tokenFileAuth, error) {
+	auth := &tokenFileAuth{filename: filename}
+	err := auth.load()
+	if err != nil {
+		return nil, err
+	}
+	return auth, nil
+}
+
+func (a *tokenFileAuth) load() error {
+	loadedAt := time.Now()
+	content, err := ioutil.ReadFile(a.filename)
+	if err != nil {
+		return fmt.Errorf("Unable to read token file %s: %v", a.filename, err)
+	}
+	token := strings.TrimSpace(string(content))
+	if token == "" {
+		return fmt.Errorf("Token file %s is empty", a.filename)
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.token = token
+	a.loadedAt = loadedAt
+	return nil
+}
+
+// changedOnDisk checks whether the token file has been modified since the
+// token was read
+func (a *tokenFileAuth) changedOnDisk() bool {
+	a.mutex.RLock()
+	loadedAt := a.loadedAt
+	a.mutex.RUnlock()
+
+	info, err := os.Stat(a.filename)
+	return err == nil && info.ModTime().After(loadedAt)
+}
+
+// authenticate adds the current token to the request. When the changed file
+// can't be read, the previous token is used.
+func (a *tokenFileAuth) authenticate(request *http.Request) {
+	if a.changedOnDisk() {
+		logging.Info(request.Context(), "Token file changed on disk, reloading")
+		err := a.load()
+		if err != nil {
+			logging.Error(request.Context(), "Unable to reload token file", "error", err)
+		}
+	}
+
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+	request.Header.Set("Authorization", "Bearer "+a.token)
+}
+
+// newAuthenticator constructs authenticator for given configuration
+func newAuthenticator(configuration AuthConfiguration) (authenticator, error) {
+	configured := 0
+	for _, enabled := range []bool{configuration.Token != "", configuration.TokenFile != "", configuration.Username != ""} {
+		if enabled {
+			configured++
+		}
+	}
+	if configured > 1 {
+		return nil, errors.New("Only one of token, token file or basic authentication can be configured for the controller")
+	}
+
+	switch {
+	case configuration.Token != "":
+		return staticTokenAuth{token: configuration.Token}, nil
+	case configuration.TokenFile != "":
+		return newTokenFileAuth(configuration.TokenFile)
+	case configuration.Username != "":
+		return basicAuth{username: configuration.Username, password: configuration.Password}, nil
+	default:
+		return noAuth{}, nil
+	}
+}
@@ -0,0 +1,168 @@
+/*
+Copyright © 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"sync"
+	"time"
+)
+
+// States of the circuit breaker
+const (
+	// BreakerClosed means that the controller works and all calls are made
+	BreakerClosed = "closed"
+
+	// BreakerOpen means that the controller failed repeatedly and calls are
+	// short-circuited without contacting the controller
+	BreakerOpen = "open"
+
+	// BreakerHalfOpen means that the open duration elapsed and one trial call
+	// is made to check whether the controller is available again, other calls
+	// are short-circuited until it finishes
+	BreakerHalfOpen = "half-open"
+)
+
+// Default values used for circuit breaker settings that are not set
+const (
+	DefaultFailureThreshold = 5
+	DefaultOpenDuration     = 30 * time.Second
+)
+
+// BreakerStatus represents the current state of circuit breaker, used to
+// display it to the user.
+//
+//	State: one of BreakerClosed, BreakerOpen and BreakerHalfOpen
+//	ConsecutiveFailures: number of failed calls since the last successful one
+//	LastError: error returned by the last failed call
+//	LastFailureAt: time of the last failed call
+//	OpenedAt: time when the breaker has been opened
+//	RetryAt: time when the next call to the controller will be made
+type BreakerStatus struct {
+	State               string
+	ConsecutiveFailures int
+	LastError           string
+	LastFailureAt       time.Time
+	OpenedAt            time.Time
+	RetryAt             time.Time
+}
+
+// CircuitBreaker stops calling the controller after repeated failures, so the
+// UI can respond immediately while the controller is down.
+type CircuitBreaker struct {
+	failureThreshold int
+	openDuration     time.Duration
+
+	mutex  sync.Mutex
+	status BreakerStatus
+
+	// probing is set while the trial call made in half-open state is running
+	probing bool
+
+	// now returns the current time, it is replaced in unit tests
+	now func() time.Time
+}
+
+// NewCircuitBreaker constructs new circuit breaker that opens after given
+// number of consecutive failures and stays open for given duration
+func NewCircuitBreaker(failureThreshold int, openDuration time.Duration) *CircuitBreaker {
+	if failureThreshold <= 0 {
+		failureThreshold = DefaultFailureThreshold
+	}
+	if openDuration <= 0 {
+		openDuration = DefaultOpenDuration
+	}
+	return &CircuitBreaker{
+		failureThreshold: failureThreshold,
+		openDuration:     openDuration,
+		status:           BreakerStatus{State: BreakerClosed},
+		now:              time.Now,
+	}
+}
+
+// Allow checks whether a call to the controller can be made. When the open
+// duration elapsed, the breaker goes to half-open state and lets exactly one
+// trial call through. The caller needs to report result of allowed call by
+// RecordSuccess, RecordFailure or Release.
+func (b *CircuitBreaker) Allow() bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	switch b.status.State {
+	case BreakerClosed:
+		return true
+	case BreakerOpen:
+		if !b.now().After(b.status.RetryAt) {
+			return false
+		}
+		b.status.State = BreakerHalfOpen
+	}
+
+	if b.probing {
+		return false
+	}
+	b.probing = true
+	return true
+}
+
+// RecordSuccess closes the breaker after successful call
+func (b *CircuitBreaker) RecordSuccess() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.status = BreakerStatus{State: BreakerClosed}
+	b.probing = false
+}
+
+// Release reports allowed call that finished without telling anything about
+// the controller, for example the one cancelled by the client. Another trial
+// call can be made in half-open state then.
+func (b *CircuitBreaker) Release() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.probing = false
+}
+
+// RecordFailure records failed call and opens the breaker when the number of
+// consecutive failures reaches the threshold or when the trial call made in
+// half-open state fails
+func (b *CircuitBreaker) RecordFailure(err error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	now := b.now()
+	b.probing = false
+	b.status.ConsecutiveFailures++
+	b.status.LastError = err.Error()
+	b.status.LastFailureAt = now
+
+	if b.status.State == BreakerHalfOpen || b.status.ConsecutiveFailures >= b.failureThreshold {
+		if b.status.OpenedAt.IsZero() {
+			b.status.OpenedAt = now
+		}
+		b.status.State = BreakerOpen
+		b.status.RetryAt = now.Add(b.openDuration)
+	}
+}
+
+// Status returns copy of the current breaker status
+func (b *CircuitBreaker) Status() BreakerStatus {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	return b.status
+}
@@ -0,0 +1,188 @@
+/*
+Copyright © 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// fakeClock is time source controlled by tests
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+// newTestBreaker constructs breaker with threshold 2 and open duration one
+// minute that uses the fake clock
+func newTestBreaker(clock *fakeClock) *CircuitBreaker {
+	breaker := NewCircuitBreaker(2, time.Minute)
+	breaker.now = clock.Now
+	return breaker
+}
+
+var errTest = errors.New("connection refused")
+
+// TestCircuitBreakerStates checks state changes caused by sequence of
+// calls, each step is one Allow call followed by reported result
+func TestCircuitBreakerStates(t *testing.T) {
+	type step struct {
+		elapsed time.Duration
+		allowed bool
+		failure bool
+		state   string
+	}
+
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{
+			name: "successful calls keep breaker closed",
+			steps: []step{
+				{allowed: true, state: BreakerClosed},
+				{allowed: true, state: BreakerClosed},
+			},
+		},
+		{
+			name: "failures below threshold keep breaker closed",
+			steps: []step{
+				{allowed: true, failure: true, state: BreakerClosed},
+				{allowed: true, state: BreakerClosed},
+				{allowed: true, failure: true, state: BreakerClosed},
+			},
+		},
+		{
+			name: "consecutive failures open breaker",
+			steps: []step{
+				{allowed: true, failure: true, state: BreakerClosed},
+				{allowed: true, failure: true, state: BreakerOpen},
+				{elapsed: 30 * time.Second, allowed: false, state: BreakerOpen},
+			},
+		},
+		{
+			name: "successful trial call closes breaker",
+			steps: []step{
+				{allowed: true, failure: true, state: BreakerClosed},
+				{allowed: true, failure: true, state: BreakerOpen},
+				{elapsed: 61 * time.Second, allowed: true, state: BreakerClosed},
+				{allowed: true, failure: true, state: BreakerClosed},
+			},
+		},
+		{
+			name: "failed trial call opens breaker again",
+			steps: []step{
+				{allowed: true, failure: true, state: BreakerClosed},
+				{allowed: true, failure: true, state: BreakerOpen},
+				{elapsed: 61 * time.Second, allowed: true, failure: true, state: BreakerOpen},
+				{elapsed: 30 * time.Second, allowed: false, state: BreakerOpen},
+				{elapsed: 31 * time.Second, allowed: true, state: BreakerClosed},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := &fakeClock{now: time.Date(2022, 5, 1, 10, 0, 0, 0, time.UTC)}
+			breaker := newTestBreaker(clock)
+
+			for i, s := range tt.steps {
+				clock.now = clock.now.Add(s.elapsed)
+				allowed := breaker.Allow()
+				if allowed != s.allowed {
+					t.Fatalf("step %d: Allow() = %v, expected %v", i, allowed, s.allowed)
+				}
+				if allowed && s.failure {
+					breaker.RecordFailure(errTest)
+				} else if allowed {
+					breaker.RecordSuccess()
+				}
+				if state := breaker.Status().State; state != s.state {
+					t.Fatalf("step %d: state %s, expected %s", i, state, s.state)
+				}
+			}
+		})
+	}
+}
+
+// TestCircuitBreakerSingleProbe checks that only one trial call is allowed
+// in half-open state
+func TestCircuitBreakerSingleProbe(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2022, 5, 1, 10, 0, 0, 0, time.UTC)}
+	breaker := newTestBreaker(clock)
+	breaker.RecordFailure(errTest)
+	breaker.RecordFailure(errTest)
+
+	clock.now = clock.now.Add(2 * time.Minute)
+	if !breaker.Allow() {
+		t.Fatal("trial call has not been allowed")
+	}
+	if state := breaker.Status().State; state != BreakerHalfOpen {
+		t.Fatalf("state %s, expected %s", state, BreakerHalfOpen)
+	}
+	for i := 0; i < 3; i++ {
+		if breaker.Allow() {
+			t.Fatal("concurrent call has been allowed during trial call")
+		}
+	}
+
+	// cancelled trial call lets another one through
+	breaker.Release()
+	if !breaker.Allow() {
+		t.Fatal("trial call has not been allowed after release")
+	}
+	breaker.RecordSuccess()
+	if !breaker.Allow() || !breaker.Allow() {
+		t.Fatal("calls are not allowed by closed breaker")
+	}
+}
+
+// TestCircuitBreakerStatus checks error details recorded by breaker
+func TestCircuitBreakerStatus(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2022, 5, 1, 10, 0, 0, 0, time.UTC)}
+	breaker := newTestBreaker(clock)
+	breaker.RecordFailure(errTest)
+	breaker.RecordFailure(errTest)
+
+	status := breaker.Status()
+	if status.ConsecutiveFailures != 2 {
+		t.Errorf("consecutive failures %d, expected 2", status.ConsecutiveFailures)
+	}
+	if status.LastError != errTest.Error() {
+		t.Errorf("last error %q, expected %q", status.LastError, errTest.Error())
+	}
+	if !status.OpenedAt.Equal(clock.now) || !status.RetryAt.Equal(clock.now.Add(time.Minute)) {
+		t.Errorf("unexpected opened at %v and retry at %v", status.OpenedAt, status.RetryAt)
+	}
+
+	breaker.RecordSuccess()
+	if status := breaker.Status(); status != (BreakerStatus{State: BreakerClosed}) {
+		t.Errorf("status has not been reset: %+v", status)
+	}
+}
+
+// TestNewCircuitBreakerDefaults checks default settings
+func TestNewCircuitBreakerDefaults(t *testing.T) {
+	breaker := NewCircuitBreaker(0, 0)
+	if breaker.failureThreshold != DefaultFailureThreshold || breaker.openDuration != DefaultOpenDuration {
+		t.Errorf("unexpected defaults %d and %v", breaker.failureThreshold, breaker.openDuration)
+	}
+}
@@ -0,0 +1,79 @@
+/*
+Copyright © 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package client contains a client for the REST API provided by the insights
+// operator instrumentation service (controller).
+package client
+
+import (
+	"context"
+	"github.com/tisnik/insights-operator-web-ui/types"
+)
+
+// ControllerClient represents all operations that can be performed against
+// the insights operator instrumentation service. All operations accept
+// context that can be used to cancel the call, for example when the UI client
+// disconnects.
+type ControllerClient interface {
+	// ListClusters reads list of all clusters registered in the controller
+	ListClusters(ctx context.Context) ([]types.Cluster, error)
+
+	// ListConfigurationProfiles reads list of all configuration profiles
+	ListConfigurationProfiles(ctx context.Context) ([]types.ConfigurationProfile, error)
+
+	// GetConfigurationProfile reads one configuration profile selected by its ID
+	GetConfigurationProfile(ctx context.Context, profileID string) (*types.ConfigurationProfile, error)
+
+	// CreateConfigurationProfile creates new configuration profile
+	CreateConfigurationProfile(ctx context.Context, username, description, configuration string) error
+
+	// ChangeConfigurationProfile stores new revision of configuration profile
+	// selected by its ID
+	ChangeConfigurationProfile(ctx context.Context, profileID, username, description, configuration string) error
+
+	// DeleteConfigurationProfile deletes configuration profile selected by its ID
+	DeleteConfigurationProfile(ctx context.Context, profileID string) error
+
+	// ListClusterConfigurations reads list of all cluster configurations
+	ListClusterConfigurations(ctx context.Context) ([]types.ClusterConfiguration, error)
+
+	// CreateClusterConfiguration creates new configuration for given cluster
+	CreateClusterConfiguration(ctx context.Context, username, cluster, reason, description, configuration string) error
+
+	// EnableClusterConfiguration enables cluster configuration selected by its ID
+	EnableClusterConfiguration(ctx context.Context, configurationID string) error
+
+	// DisableClusterConfiguration disables cluster configuration selected by its ID
+	DisableClusterConfiguration(ctx context.Context, configurationID string) error
+
+	// ListTriggers reads list of all triggers
+	ListTriggers(ctx context.Context) ([]types.Trigger, error)
+
+	// ListClusterTriggers reads list of triggers for given cluster
+	ListClusterTriggers(ctx context.Context, clusterName string) ([]types.Trigger, error)
+
+	// ActivateTrigger activates trigger selected by its ID
+	ActivateTrigger(ctx context.Context, triggerID string) error
+
+	// DeactivateTrigger deactivates trigger selected by its ID
+	DeactivateTrigger(ctx context.Context, triggerID string) error
+
+	// TriggerMustGather creates new must-gather trigger for given cluster
+	TriggerMustGather(ctx context.Context, clusterName, username, reason, link string) error
+
+	// Ping checks whether the controller is reachable
+	Ping(ctx context.Context) error
+}
@@ -0,0 +1,76 @@
+/*
+Copyright © 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+)
+
+// ErrControllerUnavailable is returned when the circuit breaker is open, i.e.
+// the controller failed repeatedly and no calls are made to it for some time
+var ErrControllerUnavailable = errors.New("Controller is unavailable")
+
+// StatusError is returned when the controller responds with unexpected HTTP
+// status code.
+type StatusError struct {
+	StatusCode int
+	Expected   string
+}
+
+// Error returns error message with the unexpected HTTP status code
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("Expected HTTP status %s, got %d", e.Expected, e.StatusCode)
+}
+
+// isTransientError checks whether the call failed because of the controller
+// or network issue, so it might succeed when repeated later
+func isTransientError(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	var statusError *StatusError
+	if errors.As(err, &statusError) {
+		return statusError.StatusCode >= http.StatusInternalServerError
+	}
+	return true
+}
+
+// IsAuthorizationError checks whether the controller refused the call
+// because of missing or invalid credentials
+func IsAuthorizationError(err error) bool {
+	var statusError *StatusError
+	if !errors.As(err, &statusError) {
+		return false
+	}
+	return statusError.StatusCode == http.StatusUnauthorized || statusError.StatusCode == http.StatusForbidden
+}
+
+// IsNotSent checks whether the call failed before the request could reach
+// the controller, i.e. the circuit breaker is open or connection could not
+// be established. For other errors (timeouts, cancelled calls, error
+// responses) the controller might have performed the operation already.
+func IsNotSent(err error) bool {
+	if errors.Is(err, ErrControllerUnavailable) {
+		return true
+	}
+	var opError *net.OpError
+	return errors.As(err, &opError) && opError.Op == "dial"
+}
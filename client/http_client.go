@@ -0,0 +1,410 @@
+/*
+Copyright © 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/tisnik/insights-operator-web-ui/logging"
+	"github.com/tisnik/insights-operator-web-ui/types"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Configuration represents configuration of the controller client.
+//
+//	URL: URL to the insights operator instrumentation service
+//	APIPrefix: part of URL that is appended before all endpoint addresses
+//	Timeouts: timeouts for individual operations
+//	Retry: retry policy for read operations
+//	CircuitBreaker: breaker used to short-circuit calls, can be nil
+//	CacheSize: maximal number of responses served when the breaker is open
+//	TLS: TLS settings for connections to the controller
+//	Auth: credentials sent to the controller
+type Configuration struct {
+	URL            string
+	APIPrefix      string
+	Timeouts       Timeouts
+	Retry          RetryPolicy
+	CircuitBreaker *CircuitBreaker
+	CacheSize      int
+	TLS            TLSConfiguration
+	Auth           AuthConfiguration
+}
+
+// HTTPControllerClient is an implementation of ControllerClient interface
+// that communicates with the controller via its REST API.
+type HTTPControllerClient struct {
+	controllerURL string
+	apiPrefix     string
+	timeouts      Timeouts
+	retry         RetryPolicy
+	breaker       *CircuitBreaker
+	cache         *responseCache
+	tlsConfig     *tls.Config
+	httpClient    *http.Client
+	auth          authenticator
+}
+
+// make sure the HTTPControllerClient implements the whole interface
+var _ ControllerClient = (*HTTPControllerClient)(nil)
+
+// NewHTTPControllerClient constructs new client for the controller with
+// given configuration. An error is returned when TLS configuration is not
+// valid, for example when CA bundle or client certificate can't be read, or
+// when credentials are not configured properly.
+func NewHTTPControllerClient(configuration Configuration) (*HTTPControllerClient, error) {
+	tlsConfig, err := newTLSConfig(configuration.TLS)
+	if err != nil {
+		return nil, err
+	}
+
+	auth, err := newAuthenticator(configuration.Auth)
+	if err != nil {
+		return nil, err
+	}
+
+	return &HTTPControllerClient{
+		controllerURL: configuration.URL,
+		apiPrefix:     configuration.APIPrefix,
+		timeouts:      configuration.Timeouts,
+		retry:         configuration.Retry,
+		breaker:       configuration.CircuitBreaker,
+		cache:         newResponseCache(configuration.CacheSize),
+		tlsConfig:     tlsConfig,
+		httpClient:    newHTTPClient(tlsConfig),
+		auth:          auth,
+	}, nil
+}
+
+// serverCommunicationError wraps the original error so it is possible to
+// check for context.DeadlineExceeded and context.Canceled by the caller
+func serverCommunicationError(err error) error {
+	return fmt.Errorf("Communication error with the server %w", err)
+}
+
+// endpointURL constructs full URL to the controller endpoint
+func (c *HTTPControllerClient) endpointURL(endpoint string) string {
+	return c.controllerURL + c.apiPrefix + endpoint
+}
+
+// allowed checks whether the circuit breaker (if any) lets the call through
+func (c *HTTPControllerClient) allowed() bool {
+	return c.breaker == nil || c.breaker.Allow()
+}
+
+// recordResult updates the circuit breaker state. Errors not caused by the
+// controller being unavailable (4xx responses, cancelled requests) are not
+// counted as failures.
+func (c *HTTPControllerClient) recordResult(err error) {
+	if c.breaker == nil {
+		return
+	}
+	if errors.Is(err, context.Canceled) {
+		c.breaker.Release()
+		return
+	}
+	if err != nil && isTransientError(err) {
+		c.breaker.RecordFailure(err)
+	} else {
+		c.breaker.RecordSuccess()
+	}
+}
+
+// performReadRequest reads data from the controller. Failed calls are retried
+// with exponential backoff within the operation timeout. When the circuit
+// breaker is open, the last successful response is returned if available.
+func (c *HTTPControllerClient) performReadRequest(ctx context.Context, operation string, url string) ([]byte, error) {
+	if !c.allowed() {
+		observeCall(operation, time.Now(), ErrControllerUnavailable)
+		cached, found := c.cache.get(url)
+		if !found {
+			return nil, ErrControllerUnavailable
+		}
+		logging.Warn(ctx, "Controller is unavailable, using cached response", "url", url, "read_at", cached.readAt)
+		markStale(ctx, cached.readAt)
+		return cached.body, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeouts.For(operation))
+	defer cancel()
+
+	started := time.Now()
+	body, err := c.performSingleReadRequest(ctx, url)
+	for attempt := 2; err != nil && isTransientError(err) && attempt <= c.retry.maxAttempts(); attempt++ {
+		logging.Warn(ctx, "Reading from controller failed, request will be retried", "url", url, "attempt", attempt, "error", err)
+		if sleepErr := sleep(ctx, c.retry.backoff(attempt)); sleepErr != nil {
+			err = serverCommunicationError(sleepErr)
+			break
+		}
+		body, err = c.performSingleReadRequest(ctx, url)
+	}
+
+	observeCall(operation, started, err)
+	c.recordResult(err)
+	if err != nil {
+		return nil, err
+	}
+
+	c.cache.put(url, body, time.Now())
+	return body, nil
+}
+
+// newRequest constructs request with credentials and ID of the request
+// handled by the UI, so messages logged by the controller can be correlated
+func (c *HTTPControllerClient) newRequest(ctx context.Context, method string, url string, payload io.Reader) (*http.Request, error) {
+	request, err := http.NewRequestWithContext(ctx, method, url, payload)
+	if err != nil {
+		return nil, fmt.Errorf("Error creating request %v", err)
+	}
+	c.auth.authenticate(request)
+	if requestID := logging.RequestID(ctx); requestID != "" {
+		request.Header.Set(logging.RequestIDHeader, requestID)
+	}
+	return request, nil
+}
+
+func (c *HTTPControllerClient) performSingleReadRequest(ctx context.Context, url string) ([]byte, error) {
+	request, err := c.newRequest(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := c.httpClient.Do(request)
+	if err != nil {
+		return nil, serverCommunicationError(err)
+	}
+	defer func() {
+		err := response.Body.Close()
+		if err != nil {
+			logging.Warn(ctx, "Unable to close response body", "error", err)
+		}
+	}()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, &StatusError{StatusCode: response.StatusCode, Expected: "200 OK"}
+	}
+
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read response body %w", err)
+	}
+
+	return body, nil
+}
+
+// performWriteRequest sends data to the controller. Write requests are not
+// idempotent, so they are never retried.
+func (c *HTTPControllerClient) performWriteRequest(ctx context.Context, operation string, url string, method string, payload io.Reader) error {
+	if !c.allowed() {
+		observeCall(operation, time.Now(), ErrControllerUnavailable)
+		return ErrControllerUnavailable
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeouts.For(operation))
+	defer cancel()
+
+	started := time.Now()
+	err := c.performSingleWriteRequest(ctx, url, method, payload)
+	observeCall(operation, started, err)
+	c.recordResult(err)
+	return err
+}
+
+func (c *HTTPControllerClient) performSingleWriteRequest(ctx context.Context, url string, method string, payload io.Reader) error {
+	request, err := c.newRequest(ctx, method, url, payload)
+	if err != nil {
+		return err
+	}
+
+	response, err := c.httpClient.Do(request)
+	if err != nil {
+		return serverCommunicationError(err)
+	}
+	defer func() {
+		err := response.Body.Close()
+		if err != nil {
+			logging.Warn(ctx, "Unable to close response body", "error", err)
+		}
+	}()
+
+	recordResponseStatus(ctx, response.StatusCode)
+	if response.StatusCode != http.StatusOK && response.StatusCode != http.StatusCreated && response.StatusCode != http.StatusAccepted {
+		return &StatusError{StatusCode: response.StatusCode, Expected: "200 OK, 201 Created or 202 Accepted"}
+	}
+	return nil
+}
+
+// readJSON performs read request and unmarshals the response body into
+// the structure provided by caller
+func (c *HTTPControllerClient) readJSON(ctx context.Context, operation string, endpoint string, target interface{}) error {
+	body, err := c.performReadRequest(ctx, operation, c.endpointURL(endpoint))
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(body, target)
+}
+
+// ListClusters reads list of all clusters registered in the controller
+func (c *HTTPControllerClient) ListClusters(ctx context.Context) ([]types.Cluster, error) {
+	clusters := []types.Cluster{}
+
+	err := c.readJSON(ctx, OperationListClusters, "client/cluster", &clusters)
+	if err != nil {
+		return nil, err
+	}
+	return clusters, nil
+}
+
+// ListConfigurationProfiles reads list of all configuration profiles
+func (c *HTTPControllerClient) ListConfigurationProfiles(ctx context.Context) ([]types.ConfigurationProfile, error) {
+	profiles := []types.ConfigurationProfile{}
+
+	err := c.readJSON(ctx, OperationListConfigurationProfiles, "client/profile", &profiles)
+	if err != nil {
+		return nil, err
+	}
+	return profiles, nil
+}
+
+// GetConfigurationProfile reads one configuration profile selected by its ID
+func (c *HTTPControllerClient) GetConfigurationProfile(ctx context.Context, profileID string) (*types.ConfigurationProfile, error) {
+	var profile types.ConfigurationProfile
+
+	err := c.readJSON(ctx, OperationGetConfigurationProfile, "client/profile/"+url.PathEscape(profileID), &profile)
+	if err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
+
+// CreateConfigurationProfile creates new configuration profile
+func (c *HTTPControllerClient) CreateConfigurationProfile(ctx context.Context, username, description, configuration string) error {
+	query := "username=" + url.QueryEscape(username) + "&description=" + url.QueryEscape(description)
+	url := c.endpointURL("client/profile?" + query)
+
+	return c.performWriteRequest(ctx, OperationCreateConfigurationProfile, url, http.MethodPost, strings.NewReader(configuration))
+}
+
+// ChangeConfigurationProfile stores new revision of configuration profile
+// selected by its ID
+func (c *HTTPControllerClient) ChangeConfigurationProfile(ctx context.Context, profileID, username, description, configuration string) error {
+	query := "username=" + url.QueryEscape(username) + "&description=" + url.QueryEscape(description)
+	url := c.endpointURL("client/profile/" + url.PathEscape(profileID) + "?" + query)
+
+	return c.performWriteRequest(ctx, OperationChangeConfigurationProfile, url, http.MethodPut, strings.NewReader(configuration))
+}
+
+// DeleteConfigurationProfile deletes configuration profile selected by its ID
+func (c *HTTPControllerClient) DeleteConfigurationProfile(ctx context.Context, profileID string) error {
+	url := c.endpointURL("client/profile/" + url.PathEscape(profileID))
+	return c.performWriteRequest(ctx, OperationDeleteConfigurationProfile, url, http.MethodDelete, nil)
+}
+
+// ListClusterConfigurations reads list of all cluster configurations
+func (c *HTTPControllerClient) ListClusterConfigurations(ctx context.Context) ([]types.ClusterConfiguration, error) {
+	configurations := []types.ClusterConfiguration{}
+
+	err := c.readJSON(ctx, OperationListClusterConfigurations, "client/configuration", &configurations)
+	if err != nil {
+		return nil, err
+	}
+	return configurations, nil
+}
+
+// CreateClusterConfiguration creates new configuration for given cluster
+func (c *HTTPControllerClient) CreateClusterConfiguration(ctx context.Context, username, cluster, reason, description, configuration string) error {
+	query := "username=" + url.QueryEscape(username) + "&reason=" + url.QueryEscape(reason) + "&description=" + url.QueryEscape(description)
+	url := c.endpointURL("client/cluster/" + url.PathEscape(cluster) + "/configuration?" + query)
+
+	return c.performWriteRequest(ctx, OperationCreateClusterConfiguration, url, http.MethodPost, strings.NewReader(configuration))
+}
+
+// EnableClusterConfiguration enables cluster configuration selected by its ID
+func (c *HTTPControllerClient) EnableClusterConfiguration(ctx context.Context, configurationID string) error {
+	url := c.endpointURL("client/configuration/" + url.PathEscape(configurationID) + "/enable")
+	return c.performWriteRequest(ctx, OperationEnableClusterConfiguration, url, http.MethodPut, nil)
+}
+
+// DisableClusterConfiguration disables cluster configuration selected by its ID
+func (c *HTTPControllerClient) DisableClusterConfiguration(ctx context.Context, configurationID string) error {
+	url := c.endpointURL("client/configuration/" + url.PathEscape(configurationID) + "/disable")
+	return c.performWriteRequest(ctx, OperationDisableClusterConfiguration, url, http.MethodPut, nil)
+}
+
+// ListTriggers reads list of all triggers
+func (c *HTTPControllerClient) ListTriggers(ctx context.Context) ([]types.Trigger, error) {
+	var triggers []types.Trigger
+
+	err := c.readJSON(ctx, OperationListTriggers, "client/trigger", &triggers)
+	if err != nil {
+		return nil, err
+	}
+	return triggers, nil
+}
+
+// ListClusterTriggers reads list of triggers for given cluster
+func (c *HTTPControllerClient) ListClusterTriggers(ctx context.Context, clusterName string) ([]types.Trigger, error) {
+	var triggers []types.Trigger
+
+	err := c.readJSON(ctx, OperationListClusterTriggers, "client/cluster/"+url.PathEscape(clusterName)+"/trigger", &triggers)
+	if err != nil {
+		return nil, err
+	}
+	return triggers, nil
+}
+
+// ActivateTrigger activates trigger selected by its ID
+func (c *HTTPControllerClient) ActivateTrigger(ctx context.Context, triggerID string) error {
+	url := c.endpointURL("client/trigger/" + url.PathEscape(triggerID) + "/activate")
+	return c.performWriteRequest(ctx, OperationActivateTrigger, url, http.MethodPut, nil)
+}
+
+// DeactivateTrigger deactivates trigger selected by its ID
+func (c *HTTPControllerClient) DeactivateTrigger(ctx context.Context, triggerID string) error {
+	url := c.endpointURL("client/trigger/" + url.PathEscape(triggerID) + "/deactivate")
+	return c.performWriteRequest(ctx, OperationDeactivateTrigger, url, http.MethodPut, nil)
+}
+
+// TriggerMustGather creates new must-gather trigger for given cluster
+func (c *HTTPControllerClient) TriggerMustGather(ctx context.Context, clusterName, username, reason, link string) error {
+	query := "username=" + url.QueryEscape(username) + "&reason=" + url.QueryEscape(reason) + "&link=" + url.QueryEscape(link)
+	url := c.endpointURL("client/cluster/" + url.PathEscape(clusterName) + "/trigger/must-gather?" + query)
+
+	return c.performWriteRequest(ctx, OperationTriggerMustGather, url, http.MethodPost, nil)
+}
+
+// Ping reads the main endpoint of the controller. The call bypasses the
+// circuit breaker and the response cache, so it reports whether the
+// controller is reachable right now, and it does not change breaker state.
+func (c *HTTPControllerClient) Ping(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, c.timeouts.For(OperationPing))
+	defer cancel()
+
+	started := time.Now()
+	_, err := c.performSingleReadRequest(ctx, c.endpointURL(""))
+	observeCall(OperationPing, started, err)
+	return err
+}
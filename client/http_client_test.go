@@ -0,0 +1,249 @@
+/*
+Copyright © 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// clusterList is response of the controller used by tests
+const clusterList = `[{"id":1,"name":"cluster-1"}]`
+
+// testController is fake controller that responds with configured status
+// code and counts calls
+type testController struct {
+	status int32
+	calls  int32
+}
+
+func (c *testController) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	atomic.AddInt32(&c.calls, 1)
+	status := int(atomic.LoadInt32(&c.status))
+	writer.WriteHeader(status)
+	if status == http.StatusOK {
+		_, _ = writer.Write([]byte(clusterList))
+	}
+}
+
+func (c *testController) setStatus(status int) {
+	atomic.StoreInt32(&c.status, int32(status))
+}
+
+func (c *testController) callCount() int {
+	return int(atomic.LoadInt32(&c.calls))
+}
+
+// newTestClient starts fake controller and constructs client for it
+func newTestClient(t *testing.T, status int, configuration Configuration) (*HTTPControllerClient, *testController) {
+	t.Helper()
+	controller := &testController{status: int32(status)}
+	server := httptest.NewServer(controller)
+	t.Cleanup(server.Close)
+
+	configuration.URL = server.URL
+	configuration.APIPrefix = "/api/v1/"
+	c, err := NewHTTPControllerClient(configuration)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c, controller
+}
+
+// TestReadRetries checks how many attempts are made for read call
+func TestReadRetries(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		attempts int
+		failed   bool
+	}{
+		{"successful call", http.StatusOK, 1, false},
+		{"server error is retried", http.StatusServiceUnavailable, 3, true},
+		{"client error is not retried", http.StatusNotFound, 1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, controller := newTestClient(t, tt.status, Configuration{
+				Retry: RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond},
+			})
+
+			clusters, err := c.ListClusters(context.Background())
+			if (err != nil) != tt.failed {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if !tt.failed && len(clusters) != 1 {
+				t.Errorf("unexpected clusters %v", clusters)
+			}
+			if controller.callCount() != tt.attempts {
+				t.Errorf("%d attempts made, expected %d", controller.callCount(), tt.attempts)
+			}
+		})
+	}
+}
+
+// TestReadRetriesStopAtDeadline checks that retries are not made after the
+// operation timeout elapses, even when more attempts are allowed
+func TestReadRetriesStopAtDeadline(t *testing.T) {
+	timeout := 200 * time.Millisecond
+	c, controller := newTestClient(t, http.StatusServiceUnavailable, Configuration{
+		Timeouts: Timeouts{Operations: map[string]time.Duration{OperationListClusters: timeout}},
+		Retry:    RetryPolicy{MaxAttempts: 1000, InitialBackoff: 50 * time.Millisecond, MaxBackoff: 50 * time.Millisecond},
+	})
+
+	started := time.Now()
+	_, err := c.ListClusters(context.Background())
+	elapsed := time.Since(started)
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("error %v, expected deadline exceeded", err)
+	}
+	if elapsed > timeout+100*time.Millisecond {
+		t.Errorf("call took %v, timeout is %v", elapsed, timeout)
+	}
+	if calls := controller.callCount(); calls < 2 || calls > 10 {
+		t.Errorf("%d attempts made within timeout", calls)
+	}
+}
+
+// TestCachedResponseWhenBreakerOpen checks that the last successful
+// response is returned and marked as stale while the breaker is open
+func TestCachedResponseWhenBreakerOpen(t *testing.T) {
+	breaker := NewCircuitBreaker(1, time.Hour)
+	c, controller := newTestClient(t, http.StatusOK, Configuration{
+		Retry:          RetryPolicy{MaxAttempts: 1},
+		CircuitBreaker: breaker,
+	})
+
+	_, err := c.ListClusters(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	controller.setStatus(http.StatusInternalServerError)
+	_, err = c.ListClusters(context.Background())
+	if err == nil {
+		t.Fatal("error expected")
+	}
+	if state := breaker.Status().State; state != BreakerOpen {
+		t.Fatalf("state %s, expected %s", state, BreakerOpen)
+	}
+
+	calls := controller.callCount()
+	ctx := WithStaleMarker(context.Background())
+	clusters, err := c.ListClusters(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(clusters) != 1 || clusters[0].Name != "cluster-1" {
+		t.Errorf("unexpected clusters %v", clusters)
+	}
+	if controller.callCount() != calls {
+		t.Error("controller has been called while breaker is open")
+	}
+	if StaleSince(ctx).IsZero() {
+		t.Error("response served from cache is not marked as stale")
+	}
+
+	// nothing is cached for other endpoints
+	_, err = c.ListTriggers(ctx)
+	if !errors.Is(err, ErrControllerUnavailable) {
+		t.Errorf("error %v, expected %v", err, ErrControllerUnavailable)
+	}
+}
+
+// TestFreshResponseIsNotStale checks that stale marker is not set when the
+// controller responds
+func TestFreshResponseIsNotStale(t *testing.T) {
+	c, _ := newTestClient(t, http.StatusOK, Configuration{CircuitBreaker: NewCircuitBreaker(1, time.Hour)})
+
+	ctx := WithStaleMarker(context.Background())
+	_, err := c.ListClusters(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !StaleSince(ctx).IsZero() {
+		t.Error("fresh response is marked as stale")
+	}
+}
+
+// TestWritesAreNotRetried checks that failed write call is made only once
+func TestWritesAreNotRetried(t *testing.T) {
+	c, controller := newTestClient(t, http.StatusServiceUnavailable, Configuration{
+		Retry: RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond},
+	})
+
+	err := c.EnableClusterConfiguration(context.Background(), "1")
+	if err == nil {
+		t.Fatal("error expected")
+	}
+	if controller.callCount() != 1 {
+		t.Errorf("%d attempts made, expected 1", controller.callCount())
+	}
+}
+
+// TestIsNotSent checks which failed must-gather calls surely have not
+// reached the controller
+func TestIsNotSent(t *testing.T) {
+	// controller that is not listening
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+	c, err := NewHTTPControllerClient(Configuration{URL: closed.URL, APIPrefix: "/api/v1/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = c.TriggerMustGather(context.Background(), "cluster-1", "jdoe", "crash loop", "")
+	if err == nil || !IsNotSent(err) {
+		t.Errorf("refused connection should not be sent: %v", err)
+	}
+
+	// controller that does not respond in time
+	release := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		<-release
+	}))
+	defer slow.Close()
+	defer close(release)
+	c, err = NewHTTPControllerClient(Configuration{
+		URL:       slow.URL,
+		APIPrefix: "/api/v1/",
+		Timeouts:  Timeouts{Operations: map[string]time.Duration{OperationTriggerMustGather: 50 * time.Millisecond}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = c.TriggerMustGather(context.Background(), "cluster-1", "jdoe", "crash loop", "")
+	if err == nil || IsNotSent(err) {
+		t.Errorf("timed out call might have been sent: %v", err)
+	}
+
+	// controller responded with an error
+	c, _ = newTestClient(t, http.StatusInternalServerError, Configuration{})
+	err = c.TriggerMustGather(context.Background(), "cluster-1", "jdoe", "crash loop", "")
+	if err == nil || IsNotSent(err) {
+		t.Errorf("server error might have been sent: %v", err)
+	}
+
+	if !IsNotSent(ErrControllerUnavailable) {
+		t.Error("call refused by circuit breaker should not be sent")
+	}
+}
@@ -0,0 +1,113 @@
+/*
+Copyright © 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"errors"
+	"github.com/tisnik/insights-operator-web-ui/metrics"
+	"time"
+)
+
+// Kinds of errors counted by controller error metric
+const (
+	errorKindUnavailable   = "unavailable"
+	errorKindTimeout       = "timeout"
+	errorKindHTTPStatus    = "http_status"
+	errorKindCommunication = "communication"
+)
+
+// Metrics of calls to the controller, labelled by operation
+var (
+	controllerCallDuration = metrics.NewHistogramVec(
+		"insights_web_ui_controller_call_duration_seconds",
+		"Duration of calls to the controller including retries.",
+		nil, "operation")
+
+	controllerCallErrors = metrics.NewCounterVec(
+		"insights_web_ui_controller_call_errors_total",
+		"Number of failed calls to the controller by kind of error.",
+		"operation", "kind")
+)
+
+// RegisterMetrics adds metrics of calls to the controller and state of the
+// circuit breaker (if any) to registry
+func RegisterMetrics(registry *metrics.Registry, breaker *CircuitBreaker) error {
+	err := registry.Register(controllerCallDuration, controllerCallErrors)
+	if err != nil || breaker == nil {
+		return err
+	}
+	return registry.Register(breakerStateMetric(breaker), breakerFailuresMetric(breaker))
+}
+
+// breakerStateMetric exposes the current state of circuit breaker, series
+// for the current state has value 1, other ones 0
+func breakerStateMetric(breaker *CircuitBreaker) metrics.Collector {
+	return metrics.NewGaugeFunc(
+		"insights_web_ui_controller_circuit_breaker_state",
+		"Current state of the circuit breaker protecting calls to the controller.",
+		[]string{"state"},
+		func() []metrics.LabeledValue {
+			current := breaker.Status().State
+			var values []metrics.LabeledValue
+			for _, state := range []string{BreakerClosed, BreakerOpen, BreakerHalfOpen} {
+				value := 0.0
+				if state == current {
+					value = 1
+				}
+				values = append(values, metrics.LabeledValue{LabelValues: []string{state}, Value: value})
+			}
+			return values
+		})
+}
+
+// breakerFailuresMetric exposes number of failed calls since the last
+// successful one
+func breakerFailuresMetric(breaker *CircuitBreaker) metrics.Collector {
+	return metrics.NewGaugeFunc(
+		"insights_web_ui_controller_consecutive_failures",
+		"Number of failed calls to the controller since the last successful one.",
+		nil,
+		func() []metrics.LabeledValue {
+			return []metrics.LabeledValue{{Value: float64(breaker.Status().ConsecutiveFailures)}}
+		})
+}
+
+// errorKind classifies error returned by the controller call
+func errorKind(err error) string {
+	var statusError *StatusError
+	switch {
+	case errors.Is(err, ErrControllerUnavailable):
+		return errorKindUnavailable
+	case errors.Is(err, context.DeadlineExceeded):
+		return errorKindTimeout
+	case errors.As(err, &statusError):
+		return errorKindHTTPStatus
+	}
+	return errorKindCommunication
+}
+
+// observeCall records duration and result of the controller call. Calls
+// short-circuited by the circuit breaker are counted as errors only.
+func observeCall(operation string, started time.Time, err error) {
+	if !errors.Is(err, ErrControllerUnavailable) {
+		controllerCallDuration.Observe(time.Since(started).Seconds(), operation)
+	}
+	if err != nil {
+		controllerCallErrors.Inc(operation, errorKind(err))
+	}
+}
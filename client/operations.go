@@ -0,0 +1,65 @@
+/*
+Copyright © 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import "time"
+
+// Names of all operations provided by the controller client. These names are
+// used as keys in the timeouts configuration.
+const (
+	OperationListClusters                = "list_clusters"
+	OperationListConfigurationProfiles   = "list_configuration_profiles"
+	OperationGetConfigurationProfile     = "get_configuration_profile"
+	OperationCreateConfigurationProfile  = "create_configuration_profile"
+	OperationChangeConfigurationProfile  = "change_configuration_profile"
+	OperationDeleteConfigurationProfile  = "delete_configuration_profile"
+	OperationListClusterConfigurations   = "list_cluster_configurations"
+	OperationCreateClusterConfiguration  = "create_cluster_configuration"
+	OperationEnableClusterConfiguration  = "enable_cluster_configuration"
+	OperationDisableClusterConfiguration = "disable_cluster_configuration"
+	OperationListTriggers                = "list_triggers"
+	OperationListClusterTriggers         = "list_cluster_triggers"
+	OperationActivateTrigger             = "activate_trigger"
+	OperationDeactivateTrigger           = "deactivate_trigger"
+	OperationTriggerMustGather           = "trigger_must_gather"
+	OperationPing                        = "ping"
+)
+
+// DefaultTimeout is used for all operations without explicitly configured
+// timeout
+const DefaultTimeout = 10 * time.Second
+
+// Timeouts represents timeouts for calls made to the controller.
+//
+//	Default: timeout used for operations not listed in Operations map
+//	Operations: timeouts for individual operations
+type Timeouts struct {
+	Default    time.Duration
+	Operations map[string]time.Duration
+}
+
+// For returns timeout that needs to be used for given operation
+func (t Timeouts) For(operation string) time.Duration {
+	timeout, found := t.Operations[operation]
+	if found && timeout > 0 {
+		return timeout
+	}
+	if t.Default > 0 {
+		return t.Default
+	}
+	return DefaultTimeout
+}
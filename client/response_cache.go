@@ -0,0 +1,93 @@
+/*
+Copyright © 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// DefaultCacheSize is the maximal number of responses kept in cache, the
+// least recently used ones are dropped first
+const DefaultCacheSize = 256
+
+// cachedResponse is body of the last successful response for one URL
+// together with time it has been read from the controller
+type cachedResponse struct {
+	url    string
+	body   []byte
+	readAt time.Time
+}
+
+// responseCache stores the last successful response for URLs read from the
+// controller. Cached responses are used when the circuit breaker is open.
+type responseCache struct {
+	mutex    sync.Mutex
+	maxSize  int
+	order    *list.List
+	elements map[string]*list.Element
+}
+
+func newResponseCache(maxSize int) *responseCache {
+	if maxSize <= 0 {
+		maxSize = DefaultCacheSize
+	}
+	return &responseCache{
+		maxSize:  maxSize,
+		order:    list.New(),
+		elements: map[string]*list.Element{},
+	}
+}
+
+func (c *responseCache) get(url string) (cachedResponse, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	element, found := c.elements[url]
+	if !found {
+		return cachedResponse{}, false
+	}
+	c.order.MoveToFront(element)
+	return *element.Value.(*cachedResponse), true
+}
+
+func (c *responseCache) put(url string, body []byte, readAt time.Time) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if element, found := c.elements[url]; found {
+		element.Value = &cachedResponse{url: url, body: body, readAt: readAt}
+		c.order.MoveToFront(element)
+		return
+	}
+
+	c.elements[url] = c.order.PushFront(&cachedResponse{url: url, body: body, readAt: readAt})
+	for c.order.Len() > c.maxSize {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.elements, oldest.Value.(*cachedResponse).url)
+	}
+}
+
+// len returns number of cached responses
+func (c *responseCache) len() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.order.Len()
+}
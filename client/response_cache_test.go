@@ -0,0 +1,74 @@
+/*
+Copyright © 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"testing"
+	"time"
+)
+
+// TestResponseCacheEviction checks that the least recently used response is
+// dropped when cache is full
+func TestResponseCacheEviction(t *testing.T) {
+	readAt := time.Date(2022, 5, 1, 10, 0, 0, 0, time.UTC)
+	cache := newResponseCache(2)
+	cache.put("a", []byte("A"), readAt)
+	cache.put("b", []byte("B"), readAt)
+
+	// "a" becomes the most recently used response
+	if _, found := cache.get("a"); !found {
+		t.Fatal("response a not found")
+	}
+	cache.put("c", []byte("C"), readAt)
+
+	tests := []struct {
+		url   string
+		found bool
+	}{
+		{"a", true},
+		{"b", false},
+		{"c", true},
+	}
+	for _, tt := range tests {
+		if _, found := cache.get(tt.url); found != tt.found {
+			t.Errorf("response %s found: %v, expected %v", tt.url, found, tt.found)
+		}
+	}
+	if cache.len() != 2 {
+		t.Errorf("cache contains %d responses, expected 2", cache.len())
+	}
+}
+
+// TestResponseCacheReplace checks that newer response replaces older one
+func TestResponseCacheReplace(t *testing.T) {
+	first := time.Date(2022, 5, 1, 10, 0, 0, 0, time.UTC)
+	second := first.Add(time.Minute)
+	cache := newResponseCache(0)
+	cache.put("a", []byte("old"), first)
+	cache.put("a", []byte("new"), second)
+
+	cached, found := cache.get("a")
+	if !found || string(cached.body) != "new" || !cached.readAt.Equal(second) {
+		t.Errorf("unexpected cached response %+v", cached)
+	}
+	if cache.len() != 1 {
+		t.Errorf("cache contains %d responses, expected 1", cache.len())
+	}
+	if cache.maxSize != DefaultCacheSize {
+		t.Errorf("cache size %d, expected %d", cache.maxSize, DefaultCacheSize)
+	}
+}
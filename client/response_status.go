@@ -0,0 +1,45 @@
+/*
+Copyright © 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+)
+
+// responseStatusKey is used to store ResponseStatus in context
+type responseStatusKey struct{}
+
+// ResponseStatus receives HTTP status code returned by the controller. It is
+// useful for successful write operations that don't return any other
+// information, for example to record the status in audit log.
+type ResponseStatus struct {
+	Code int
+}
+
+// WithResponseStatus returns context that makes write operations store HTTP
+// status code returned by the controller into given structure
+func WithResponseStatus(ctx context.Context, status *ResponseStatus) context.Context {
+	return context.WithValue(ctx, responseStatusKey{}, status)
+}
+
+// recordResponseStatus stores HTTP status code if the caller asked for it
+func recordResponseStatus(ctx context.Context, code int) {
+	status, ok := ctx.Value(responseStatusKey{}).(*ResponseStatus)
+	if ok && status != nil {
+		status.Code = code
+	}
+}
@@ -0,0 +1,89 @@
+/*
+Copyright © 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"math/rand"
+	"time"
+)
+
+// Default values used for retry policy fields that are not set
+const (
+	DefaultMaxAttempts    = 3
+	DefaultInitialBackoff = 100 * time.Millisecond
+	DefaultMaxBackoff     = 2 * time.Second
+)
+
+// RetryPolicy represents how idempotent (read) calls are retried when the
+// controller can't be reached or responds with 5xx status code.
+//
+//	MaxAttempts: total number of attempts, including the first one
+//	InitialBackoff: wait time before the second attempt
+//	MaxBackoff: upper limit for wait time between attempts
+type RetryPolicy struct {
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+func (p RetryPolicy) maxAttempts() int {
+	if p.MaxAttempts > 0 {
+		return p.MaxAttempts
+	}
+	return DefaultMaxAttempts
+}
+
+// backoff computes wait time before given attempt (counted from 1). The wait
+// time grows exponentially and random jitter is applied so that all UI
+// instances don't hit restarted controller at the same moment.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	initial := p.InitialBackoff
+	if initial <= 0 {
+		initial = DefaultInitialBackoff
+	}
+	maximum := p.MaxBackoff
+	if maximum <= 0 {
+		maximum = DefaultMaxBackoff
+	}
+
+	backoff := initial
+	for i := 1; i < attempt-1 && backoff < maximum; i++ {
+		backoff *= 2
+	}
+	if backoff > maximum {
+		backoff = maximum
+	}
+
+	// equal jitter: half of the backoff is fixed, the rest is random
+	half := backoff / 2
+	// #nosec G404
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// sleep waits for given duration or until the context is done
+func sleep(ctx context.Context, duration time.Duration) error {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,65 @@
+/*
+Copyright © 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type staleMarkerKey struct{}
+
+// staleMarker remembers the oldest cached response served to one UI request
+type staleMarker struct {
+	mutex  sync.Mutex
+	readAt time.Time
+}
+
+// WithStaleMarker returns context that records whether any response read
+// with it has been served from cache, because the controller was not
+// available
+func WithStaleMarker(ctx context.Context) context.Context {
+	return context.WithValue(ctx, staleMarkerKey{}, &staleMarker{})
+}
+
+// StaleSince returns time when the oldest cached response served within the
+// context has been read from the controller. Zero time is returned when all
+// responses came from the controller directly.
+func StaleSince(ctx context.Context) time.Time {
+	marker, ok := ctx.Value(staleMarkerKey{}).(*staleMarker)
+	if !ok {
+		return time.Time{}
+	}
+	marker.mutex.Lock()
+	defer marker.mutex.Unlock()
+	return marker.readAt
+}
+
+// markStale records that response read at given time has been served from
+// cache
+func markStale(ctx context.Context, readAt time.Time) {
+	marker, ok := ctx.Value(staleMarkerKey{}).(*staleMarker)
+	if !ok {
+		return
+	}
+	marker.mutex.Lock()
+	defer marker.mutex.Unlock()
+	if marker.readAt.IsZero() || readAt.Before(marker.readAt) {
+		marker.readAt = readAt
+	}
+}
@@ -0,0 +1,138 @@
+/*
+Copyright © 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+)
+
+// TLSConfiguration represents TLS settings used for connections to the
+// controller. All fields are optional.
+//
+//	CAFile: bundle with CA certificates (PEM) used to verify the controller
+//	CertFile: client certificate (PEM) presented to the controller
+//	KeyFile: private key (PEM) for the client certificate
+//	ServerName: name used to verify controller certificate instead of host
+type TLSConfiguration struct {
+	CAFile     string
+	CertFile   string
+	KeyFile    string
+	ServerName string
+}
+
+// newTLSConfig constructs TLS configuration for connections to the controller
+func newTLSConfig(configuration TLSConfiguration) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: configuration.ServerName,
+	}
+
+	if configuration.CAFile != "" {
+		// #nosec G304
+		bundle, err := ioutil.ReadFile(configuration.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to read CA bundle %s: %v", configuration.CAFile, err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(bundle) {
+			return nil, fmt.Errorf("No CA certificate found in %s", configuration.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if configuration.CertFile != "" || configuration.KeyFile != "" {
+		certificate, err := tls.LoadX509KeyPair(configuration.CertFile, configuration.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to load client certificate %s and key %s: %v",
+				configuration.CertFile, configuration.KeyFile, err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+
+	return tlsConfig, nil
+}
+
+// newHTTPClient constructs HTTP client that uses given TLS configuration
+func newHTTPClient(tlsConfig *tls.Config) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	return &http.Client{Transport: transport}
+}
+
+// describeTLSError adds hint about possible cause of TLS handshake failure
+func describeTLSError(err error) string {
+	var unknownAuthority x509.UnknownAuthorityError
+	var hostnameError x509.HostnameError
+	var invalidCertificate x509.CertificateInvalidError
+	var recordHeaderError tls.RecordHeaderError
+
+	switch {
+	case errors.As(err, &unknownAuthority):
+		return "controller certificate is signed by unknown authority, check the CA bundle"
+	case errors.As(err, &hostnameError):
+		return "controller certificate does not match host name, check the server name override"
+	case errors.As(err, &invalidCertificate):
+		return "controller certificate is not valid (expired or not yet valid)"
+	case errors.As(err, &recordHeaderError):
+		return "controller does not speak TLS, check the controller URL scheme"
+	default:
+		return "controller might require client certificate, check client certificate and key"
+	}
+}
+
+// CheckTLSConnection performs TLS handshake with the controller to find out
+// configuration problems early. Nothing is checked when the controller URL
+// does not use HTTPS.
+func (c *HTTPControllerClient) CheckTLSConnection(ctx context.Context) error {
+	controllerURL, err := url.Parse(c.controllerURL)
+	if err != nil {
+		return fmt.Errorf("Invalid controller URL %s: %v", c.controllerURL, err)
+	}
+	if controllerURL.Scheme != "https" {
+		return nil
+	}
+
+	address := controllerURL.Host
+	if controllerURL.Port() == "" {
+		address = net.JoinHostPort(controllerURL.Hostname(), "443")
+	}
+
+	tlsConfig := c.tlsConfig.Clone()
+	if tlsConfig.ServerName == "" {
+		tlsConfig.ServerName = controllerURL.Hostname()
+	}
+
+	dialer := tls.Dialer{Config: tlsConfig}
+	connection, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		var netError *net.OpError
+		if errors.As(err, &netError) && netError.Op == "dial" {
+			return fmt.Errorf("Unable to connect to controller at %s: %v", address, err)
+		}
+		return fmt.Errorf("TLS handshake with controller at %s failed (%s): %v",
+			address, describeTLSError(err), err)
+	}
+	return connection.Close()
+}
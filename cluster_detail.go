@@ -0,0 +1,144 @@
+/*
+Copyright © 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"github.com/tisnik/insights-operator-web-ui/client"
+	"github.com/tisnik/insights-operator-web-ui/router"
+	"github.com/tisnik/insights-operator-web-ui/types"
+	"net/http"
+	"sort"
+	"strconv"
+)
+
+// configuration is active when its Active field contains this value
+const activeConfiguration = "1"
+
+// ClusterDynContent represents dynamic part of HTML page with cluster
+// detail.
+//
+//	Cluster: the cluster itself
+//	Configurations: configuration history of the cluster, the newest first
+//	Triggers: all triggers for the cluster
+//	ActiveConfigurations: number of active configurations
+//	ActiveTriggers: number of active triggers
+type ClusterDynContent struct {
+	Cluster              types.Cluster                `json:"cluster"`
+	Configurations       []types.ClusterConfiguration `json:"configurations"`
+	Triggers             []types.Trigger              `json:"triggers"`
+	ActiveConfigurations int                          `json:"active_configurations"`
+	ActiveTriggers       int                          `json:"active_triggers"`
+}
+
+// NewConfigurationDynContent represents dynamic part of HTML page with form
+// for new cluster configuration.
+//
+//	Cluster: name of cluster the form is pre-filled with
+type NewConfigurationDynContent struct {
+	Cluster string
+}
+
+// findCluster finds cluster with given name or ID. Cluster configurations
+// refer to clusters by ID, so links from them use the ID.
+func findCluster(clusters []types.Cluster, nameOrID string) (types.Cluster, bool) {
+	for _, cluster := range clusters {
+		if cluster.Name == nameOrID || strconv.Itoa(cluster.ID) == nameOrID {
+			return cluster, true
+		}
+	}
+	return types.Cluster{}, false
+}
+
+// newClusterDynContent selects configurations of the cluster (they contain
+// cluster ID, not name) and counts active configurations and triggers
+func newClusterDynContent(cluster types.Cluster, configurations []types.ClusterConfiguration, triggers []types.Trigger) ClusterDynContent {
+	dynData := ClusterDynContent{
+		Cluster:        cluster,
+		Configurations: []types.ClusterConfiguration{},
+		Triggers:       triggers,
+	}
+
+	clusterID := strconv.Itoa(cluster.ID)
+	for _, configuration := range configurations {
+		if configuration.Cluster != clusterID {
+			continue
+		}
+		dynData.Configurations = append(dynData.Configurations, configuration)
+		if configuration.Active == activeConfiguration {
+			dynData.ActiveConfigurations++
+		}
+	}
+	sort.SliceStable(dynData.Configurations, func(i, j int) bool {
+		return dynData.Configurations[i].ChangedAt > dynData.Configurations[j].ChangedAt
+	})
+
+	for _, trigger := range triggers {
+		if trigger.Active == 1 {
+			dynData.ActiveTriggers++
+		}
+	}
+	return dynData
+}
+
+// clusterDetail displays cluster together with its configuration history
+// and triggers
+func clusterDetail(writer http.ResponseWriter, request *http.Request) {
+	name := router.Param(request, "name")
+
+	clusters, err := controller.ListClusters(request.Context())
+	if err != nil {
+		controllerErrorResponse(writer, request, client.OperationListClusters, err)
+		return
+	}
+	cluster, found := findCluster(clusters, name)
+	if !found {
+		errorPageResponse(writer, request, http.StatusNotFound, errorPageTemplate, ErrorPageDynContent{
+			Title:     "Not found",
+			Operation: describeOperation(client.OperationListClusters),
+			Message:   "Cluster " + name + " is not registered in the controller",
+			RetryURL:  "/list-clusters",
+		})
+		return
+	}
+
+	configurations, err := controller.ListClusterConfigurations(request.Context())
+	if err != nil {
+		controllerErrorResponse(writer, request, client.OperationListClusterConfigurations, err)
+		return
+	}
+
+	triggers, err := controller.ListClusterTriggers(request.Context(), cluster.Name)
+	if err != nil {
+		controllerErrorResponse(writer, request, client.OperationListClusterTriggers, err)
+		return
+	}
+
+	// NoCache headers
+	for k, v := range noCacheHeaders {
+		writer.Header().Set(k, v)
+	}
+
+	dynData := newClusterDynContent(cluster, configurations, triggers)
+	renderPage(writer, request, http.StatusOK, "cluster.html", dynData)
+}
+
+// newConfiguration displays form for new cluster configuration, cluster
+// name can be pre-filled by query parameter
+func newConfiguration(writer http.ResponseWriter, request *http.Request) {
+	dynData := NewConfigurationDynContent{Cluster: request.URL.Query().Get(clusterParameter)}
+	renderPage(writer, request, http.StatusOK, "new_configuration.html", dynData)
+}
@@ -0,0 +1,92 @@
+/*
+Copyright © 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"github.com/tisnik/insights-operator-web-ui/types"
+	"testing"
+)
+
+// responses in the same format as returned by the controller
+const (
+	testClustersResponse = `[
+		{"id": 1, "name": "c8590f31-e97e-4b85-b506-c45ce1911a12"},
+		{"id": 2, "name": "ffffffff-0000-4b85-b506-c45ce1911a12"}
+	]`
+	testConfigurationsResponse = `[
+		{"id": 1, "cluster": "1", "configuration": "1", "changed_at": "2020-01-01T00:00:00", "changed_by": "tester", "active": "0", "reason": "init"},
+		{"id": 2, "cluster": "2", "configuration": "1", "changed_at": "2020-01-02T00:00:00", "changed_by": "tester", "active": "1", "reason": "init"},
+		{"id": 3, "cluster": "1", "configuration": "2", "changed_at": "2020-02-01T00:00:00", "changed_by": "other", "active": "1", "reason": "more data"}
+	]`
+	testTriggersResponse = `[
+		{"id": 1, "type": "must-gather", "cluster": "c8590f31-e97e-4b85-b506-c45ce1911a12", "reason": "crash loop", "link": "", "triggered_at": "2020-01-01T00:00:00", "triggered_by": "tester", "acked_at": "", "parameters": "", "active": 1},
+		{"id": 2, "type": "must-gather", "cluster": "c8590f31-e97e-4b85-b506-c45ce1911a12", "reason": "crash loop", "link": "", "triggered_at": "2020-01-02T00:00:00", "triggered_by": "tester", "acked_at": "2020-01-03T00:00:00", "parameters": "", "active": 0}
+	]`
+)
+
+// decodeResponse decodes JSON response of the controller
+func decodeResponse(t *testing.T, response string, target interface{}) {
+	t.Helper()
+	err := json.Unmarshal([]byte(response), target)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestFindCluster checks that cluster is found by name and ID
+func TestFindCluster(t *testing.T) {
+	var clusters []types.Cluster
+	decodeResponse(t, testClustersResponse, &clusters)
+
+	for _, nameOrID := range []string{"ffffffff-0000-4b85-b506-c45ce1911a12", "2"} {
+		cluster, found := findCluster(clusters, nameOrID)
+		if !found || cluster.ID != 2 {
+			t.Errorf("cluster %s not found: %v", nameOrID, cluster)
+		}
+	}
+	_, found := findCluster(clusters, "3")
+	if found {
+		t.Error("unknown cluster found")
+	}
+}
+
+// TestNewClusterDynContent checks that configurations are selected by
+// cluster ID and sorted from the newest one
+func TestNewClusterDynContent(t *testing.T) {
+	var clusters []types.Cluster
+	var configurations []types.ClusterConfiguration
+	var triggers []types.Trigger
+	decodeResponse(t, testClustersResponse, &clusters)
+	decodeResponse(t, testConfigurationsResponse, &configurations)
+	decodeResponse(t, testTriggersResponse, &triggers)
+
+	dynData := newClusterDynContent(clusters[0], configurations, triggers)
+
+	if len(dynData.Configurations) != 2 {
+		t.Fatalf("expected 2 configurations of the cluster, got %v", dynData.Configurations)
+	}
+	if dynData.Configurations[0].ID != 3 || dynData.Configurations[1].ID != 1 {
+		t.Errorf("configurations should be sorted from the newest one: %v", dynData.Configurations)
+	}
+	if dynData.ActiveConfigurations != 1 {
+		t.Errorf("expected 1 active configuration, got %d", dynData.ActiveConfigurations)
+	}
+	if dynData.ActiveTriggers != 1 {
+		t.Errorf("expected 1 active trigger, got %d", dynData.ActiveTriggers)
+	}
+}
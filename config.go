@@ -0,0 +1,292 @@
+/*
+Copyright © 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"github.com/spf13/viper"
+	"github.com/tisnik/insights-operator-web-ui/auth"
+	"github.com/tisnik/insights-operator-web-ui/client"
+	"github.com/tisnik/insights-operator-web-ui/logging"
+	"time"
+)
+
+// name of configuration section with timeouts for controller calls
+const controllerTimeoutsSection = "controller_timeouts"
+
+// key in the controller timeouts section with default timeout
+const defaultTimeoutKey = "default"
+
+// Configuration keys for HTTP server
+const (
+	serverAddressKey             = "address"
+	serverReadTimeoutKey         = "server.read_timeout"
+	serverReadHeaderTimeoutKey   = "server.read_header_timeout"
+	serverWriteTimeoutKey        = "server.write_timeout"
+	serverIdleTimeoutKey         = "server.idle_timeout"
+	serverShutdownGracePeriodKey = "server.shutdown_grace_period"
+)
+
+// Configuration keys for TLS termination
+const (
+	tlsEnabledKey         = "tls.enabled"
+	tlsCertFileKey        = "tls.cert_file"
+	tlsKeyFileKey         = "tls.key_file"
+	tlsMinVersionKey      = "tls.min_version"
+	tlsRedirectAddressKey = "tls.redirect_address"
+)
+
+// Configuration keys for TLS connections to the controller
+const (
+	controllerCAFileKey     = "controller_tls.ca_file"
+	controllerCertFileKey   = "controller_tls.cert_file"
+	controllerKeyFileKey    = "controller_tls.key_file"
+	controllerServerNameKey = "controller_tls.server_name"
+)
+
+// Configuration keys for credentials sent to the controller
+const (
+	controllerTokenKey     = "controller_auth.token"
+	controllerTokenFileKey = "controller_auth.token_file"
+	controllerUsernameKey  = "controller_auth.username"
+	controllerPasswordKey  = "controller_auth.password"
+)
+
+// Configuration keys for user login via OpenID Connect provider
+const (
+	oidcEnabledKey         = "oidc.enabled"
+	oidcIssuerURLKey       = "oidc.issuer_url"
+	oidcClientIDKey        = "oidc.client_id"
+	oidcClientSecretKey    = "oidc.client_secret"
+	oidcRedirectURLKey     = "oidc.redirect_url"
+	oidcScopesKey          = "oidc.scopes"
+	oidcUsernameClaimKey   = "oidc.username_claim"
+	oidcGroupsClaimKey     = "oidc.groups_claim"
+	oidcSessionLifetimeKey = "oidc.session_lifetime"
+)
+
+// Configuration keys for mapping of users to roles
+const (
+	authorizationEnabledKey                  = "authorization.enabled"
+	authorizationPolicyFileKey               = "authorization.policy_file"
+	authorizationDefaultRoleKey              = "authorization.default_role"
+	authorizationViewerGroupsKey             = "authorization.viewer_groups"
+	authorizationEditorGroupsKey             = "authorization.editor_groups"
+	authorizationMustGatherApproverGroupsKey = "authorization.must_gather_approver_groups"
+	authorizationAdminGroupsKey              = "authorization.admin_groups"
+)
+
+// Configuration keys for must-gather approval and validation
+const (
+	mustGatherRequireApprovalKey  = "must_gather.require_approval"
+	mustGatherRequestsFileKey     = "must_gather.requests_file"
+	mustGatherAllowedLinkHostsKey = "must_gather.allowed_link_hosts"
+	mustGatherMinReasonLengthKey  = "must_gather.min_reason_length"
+)
+
+// Configuration keys for audit log
+const (
+	auditEnabledKey    = "audit.enabled"
+	auditFileKey       = "audit.file"
+	auditMaxSizeKey    = "audit.max_size"
+	auditMaxBackupsKey = "audit.max_backups"
+)
+
+// Configuration keys for health checks
+const (
+	healthReadinessCacheDurationKey = "health.readiness_cache_duration"
+)
+
+// Configuration keys for logging
+const (
+	loggingLevelKey          = "logging.level"
+	loggingFormatKey         = "logging.format"
+	loggingRedactedFieldsKey = "logging.redacted_fields"
+)
+
+// Configuration keys for assets (HTML templates, CSS and JavaScript files)
+const (
+	assetsLoadFromDiskKey = "assets.load_from_disk"
+	assetsDirectoryKey    = "assets.directory"
+)
+
+// Configuration keys for retry policy and circuit breaker
+const (
+	retryMaxAttemptsKey        = "controller_retry.max_attempts"
+	retryInitialBackoffKey     = "controller_retry.initial_backoff"
+	retryMaxBackoffKey         = "controller_retry.max_backoff"
+	breakerFailureThresholdKey = "circuit_breaker.failure_threshold"
+	breakerOpenDurationKey     = "circuit_breaker.open_duration"
+	breakerCacheSizeKey        = "circuit_breaker.cache_size"
+)
+
+// readControllerTimeouts reads timeouts for all calls to the controller from
+// configuration. Timeouts are specified as durations, for example "10s".
+func readControllerTimeouts() client.Timeouts {
+	timeouts := client.Timeouts{
+		Default:    client.DefaultTimeout,
+		Operations: map[string]time.Duration{},
+	}
+
+	for operation, value := range viper.GetStringMapString(controllerTimeoutsSection) {
+		timeout, err := time.ParseDuration(value)
+		if err != nil {
+			logging.Warn(context.Background(), "Invalid controller timeout", "operation", operation, "timeout", value, "error", err)
+			continue
+		}
+		if operation == defaultTimeoutKey {
+			timeouts.Default = timeout
+		} else {
+			timeouts.Operations[operation] = timeout
+		}
+	}
+
+	return timeouts
+}
+
+// readRetryPolicy reads policy for retrying read calls to the controller.
+// Default values are used for settings that are not specified.
+func readRetryPolicy() client.RetryPolicy {
+	return client.RetryPolicy{
+		MaxAttempts:    viper.GetInt(retryMaxAttemptsKey),
+		InitialBackoff: viper.GetDuration(retryInitialBackoffKey),
+		MaxBackoff:     viper.GetDuration(retryMaxBackoffKey),
+	}
+}
+
+// readCircuitBreaker constructs circuit breaker configured according to the
+// circuit_breaker section
+func readCircuitBreaker() *client.CircuitBreaker {
+	return client.NewCircuitBreaker(
+		viper.GetInt(breakerFailureThresholdKey),
+		viper.GetDuration(breakerOpenDurationKey))
+}
+
+// readServerConfiguration reads address and timeouts for the HTTP server.
+// Default values are used for timeouts that are not specified.
+func readServerConfiguration() ServerConfiguration {
+	return ServerConfiguration{
+		Address:             viper.GetString(serverAddressKey),
+		ReadTimeout:         viper.GetDuration(serverReadTimeoutKey),
+		ReadHeaderTimeout:   viper.GetDuration(serverReadHeaderTimeoutKey),
+		WriteTimeout:        viper.GetDuration(serverWriteTimeoutKey),
+		IdleTimeout:         viper.GetDuration(serverIdleTimeoutKey),
+		ShutdownGracePeriod: viper.GetDuration(serverShutdownGracePeriodKey),
+		TLS: TLSConfiguration{
+			Enabled:         viper.GetBool(tlsEnabledKey),
+			CertFile:        viper.GetString(tlsCertFileKey),
+			KeyFile:         viper.GetString(tlsKeyFileKey),
+			MinVersion:      viper.GetString(tlsMinVersionKey),
+			RedirectAddress: viper.GetString(tlsRedirectAddressKey),
+		},
+	}
+}
+
+// readControllerTLSConfiguration reads CA bundle, client certificate and
+// server name override used for connections to the controller
+func readControllerTLSConfiguration() client.TLSConfiguration {
+	return client.TLSConfiguration{
+		CAFile:     viper.GetString(controllerCAFileKey),
+		CertFile:   viper.GetString(controllerCertFileKey),
+		KeyFile:    viper.GetString(controllerKeyFileKey),
+		ServerName: viper.GetString(controllerServerNameKey),
+	}
+}
+
+// readControllerAuthConfiguration reads credentials used to authenticate
+// calls to the controller
+func readControllerAuthConfiguration() client.AuthConfiguration {
+	return client.AuthConfiguration{
+		Token:     viper.GetString(controllerTokenKey),
+		TokenFile: viper.GetString(controllerTokenFileKey),
+		Username:  viper.GetString(controllerUsernameKey),
+		Password:  viper.GetString(controllerPasswordKey),
+	}
+}
+
+// readOIDCConfiguration reads configuration of user login
+func readOIDCConfiguration() OIDCConfiguration {
+	return OIDCConfiguration{
+		Enabled: viper.GetBool(oidcEnabledKey),
+		Configuration: auth.Configuration{
+			IssuerURL:     viper.GetString(oidcIssuerURLKey),
+			ClientID:      viper.GetString(oidcClientIDKey),
+			ClientSecret:  viper.GetString(oidcClientSecretKey),
+			RedirectURL:   viper.GetString(oidcRedirectURLKey),
+			Scopes:        viper.GetStringSlice(oidcScopesKey),
+			UsernameClaim: viper.GetString(oidcUsernameClaimKey),
+			GroupsClaim:   viper.GetString(oidcGroupsClaimKey),
+		},
+		SessionLifetime: viper.GetDuration(oidcSessionLifetimeKey),
+	}
+}
+
+// readAuthorizationConfiguration reads mapping of users and groups to roles
+func readAuthorizationConfiguration() AuthorizationConfiguration {
+	return AuthorizationConfiguration{
+		Enabled:                  viper.GetBool(authorizationEnabledKey),
+		PolicyFile:               viper.GetString(authorizationPolicyFileKey),
+		DefaultRole:              viper.GetString(authorizationDefaultRoleKey),
+		ViewerGroups:             viper.GetStringSlice(authorizationViewerGroupsKey),
+		EditorGroups:             viper.GetStringSlice(authorizationEditorGroupsKey),
+		MustGatherApproverGroups: viper.GetStringSlice(authorizationMustGatherApproverGroupsKey),
+		AdminGroups:              viper.GetStringSlice(authorizationAdminGroupsKey),
+	}
+}
+
+// readAuditConfiguration reads location and rotation settings of audit log
+func readAuditConfiguration() AuditConfiguration {
+	return AuditConfiguration{
+		Enabled:    viper.GetBool(auditEnabledKey),
+		File:       viper.GetString(auditFileKey),
+		MaxSize:    viper.GetInt64(auditMaxSizeKey),
+		MaxBackups: viper.GetInt(auditMaxBackupsKey),
+	}
+}
+
+// readHealthConfiguration reads settings of health checks
+func readHealthConfiguration() HealthConfiguration {
+	return HealthConfiguration{
+		ReadinessCacheDuration: viper.GetDuration(healthReadinessCacheDurationKey),
+	}
+}
+
+// readMustGatherConfiguration reads whether must-gather needs to be approved,
+// where pending requests are stored and rules for must-gather form. Approval
+// is required unless it is disabled explicitly.
+func readMustGatherConfiguration() MustGatherConfiguration {
+	return MustGatherConfiguration{
+		RequireApproval:  !viper.IsSet(mustGatherRequireApprovalKey) || viper.GetBool(mustGatherRequireApprovalKey),
+		RequestsFile:     viper.GetString(mustGatherRequestsFileKey),
+		AllowedLinkHosts: viper.GetStringSlice(mustGatherAllowedLinkHostsKey),
+		MinReasonLength:  viper.GetInt(mustGatherMinReasonLengthKey),
+	}
+}
+
+// readLoggingConfiguration reads level and format of logged messages and
+// form fields that are not logged. Default list of redacted fields is used
+// when it is not specified.
+func readLoggingConfiguration() logging.Configuration {
+	configuration := logging.Configuration{
+		Level:  viper.GetString(loggingLevelKey),
+		Format: viper.GetString(loggingFormatKey),
+	}
+	if viper.IsSet(loggingRedactedFieldsKey) {
+		configuration.RedactedFields = viper.GetStringSlice(loggingRedactedFieldsKey)
+	}
+	return configuration
+}
@@ -0,0 +1,286 @@
+/*
+Copyright © 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"github.com/tisnik/insights-operator-web-ui/listing"
+	"github.com/tisnik/insights-operator-web-ui/logging"
+	"github.com/tisnik/insights-operator-web-ui/types"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// ContentTypeCSV represents content type text/csv used in HTTP responses
+const ContentTypeCSV = "text/csv"
+
+// query parameter that selects response format instead of Accept header
+const formatParameter = "format"
+
+// Formats list pages can be rendered in
+const (
+	formatHTML = "html"
+	formatJSON = "json"
+	formatCSV  = "csv"
+)
+
+// offeredFormats are formats of list pages in order of preference, HTML is
+// used when client accepts more formats with the same quality
+var offeredFormats = []string{formatHTML, formatJSON, formatCSV}
+
+// formatContentTypes maps formats to content types used in Accept header
+var formatContentTypes = map[string]string{
+	formatHTML: ContentTypeHTML,
+	formatJSON: ContentTypeJSON,
+	formatCSV:  ContentTypeCSV,
+}
+
+// tabularData is implemented by dynamic content of list pages, so the list
+// can be exported as JSON or CSV
+type tabularData interface {
+	// csvHeader returns names of columns
+	csvHeader() []string
+
+	// csvRecords returns one record for each item in the list
+	csvRecords() [][]string
+
+	// jsonExport returns all items matching the query together with the page
+	jsonExport() listExport
+}
+
+// listExport represents list exported as JSON, it contains all matching
+// items, not just the items of the current page.
+//
+//	Items: all items matching the query
+//	Page: query and position of the page in list
+type listExport struct {
+	Items interface{}  `json:"items"`
+	Page  listing.Page `json:"page"`
+}
+
+// acceptQuality returns quality the Accept header assigns to given content
+// type. Exact match takes precedence over type/* and */* ranges.
+func acceptQuality(accept string, contentType string) float64 {
+	mainType := strings.SplitN(contentType, "/", 2)[0]
+	quality, specificity := 0.0, -1
+
+	for _, mediaRange := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(mediaRange))
+		if err != nil {
+			continue
+		}
+
+		rangeSpecificity := -1
+		switch mediaType {
+		case contentType:
+			rangeSpecificity = 2
+		case mainType + "/*":
+			rangeSpecificity = 1
+		case "*/*":
+			rangeSpecificity = 0
+		}
+		if rangeSpecificity <= specificity {
+			continue
+		}
+
+		specificity = rangeSpecificity
+		quality = 1
+		if q, err := strconv.ParseFloat(params["q"], 64); err == nil {
+			quality = q
+		}
+	}
+	return quality
+}
+
+// negotiateFormat selects format of list page. Format given by query
+// parameter takes precedence over Accept header, HTML is used when the client
+// does not accept any offered format.
+func negotiateFormat(request *http.Request) (string, error) {
+	if format := request.URL.Query().Get(formatParameter); format != "" {
+		if _, found := formatContentTypes[format]; !found {
+			return "", fmt.Errorf("Unsupported format %q, use one of: %s", format, strings.Join(offeredFormats, ", "))
+		}
+		return format, nil
+	}
+
+	accept := request.Header.Get("Accept")
+	if accept == "" {
+		return formatHTML, nil
+	}
+
+	best, bestQuality := formatHTML, 0.0
+	for _, format := range offeredFormats {
+		quality := acceptQuality(accept, formatContentTypes[format])
+		if quality > bestQuality {
+			best, bestQuality = format, quality
+		}
+	}
+	return best, nil
+}
+
+// renderTable writes list page as JSON or CSV when the client asked for it.
+// It returns false when the page needs to be rendered as HTML.
+func renderTable(writer http.ResponseWriter, request *http.Request, status int, page string, table tabularData) bool {
+	writer.Header().Add("Vary", "Accept")
+
+	format, err := negotiateFormat(request)
+	if err != nil {
+		errorPageResponse(writer, request, http.StatusBadRequest, errorPageTemplate, ErrorPageDynContent{
+			Title:     "Unsupported format",
+			Operation: request.Method + " " + request.URL.Path,
+			Message:   err.Error(),
+			RetryURL:  request.URL.Path,
+		})
+		return true
+	}
+
+	switch format {
+	case formatJSON:
+		writeJSON(writer, request, status, table.jsonExport())
+		return true
+	case formatCSV:
+		writeCSV(writer, request, status, csvFilename(page), table)
+		return true
+	}
+	return false
+}
+
+// csvFilename derives name of downloaded file from name of the page, for
+// example clusters.csv for list_clusters.html
+func csvFilename(page string) string {
+	return strings.TrimSuffix(strings.TrimPrefix(page, "list_"), ".html") + ".csv"
+}
+
+// formatURL returns address of the current page in given format, other
+// query parameters are kept
+func formatURL(request *http.Request, format string) string {
+	return listURL(request, func(query url.Values) {
+		query.Set(formatParameter, format)
+	})
+}
+
+// writeCSV writes table as CSV file with header
+func writeCSV(writer http.ResponseWriter, request *http.Request, status int, filename string, table tabularData) {
+	writer.Header().Set("Content-Type", ContentTypeCSV+"; charset=utf-8")
+	writer.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	writer.WriteHeader(status)
+
+	output := csv.NewWriter(writer)
+	err := output.Write(table.csvHeader())
+	if err == nil {
+		err = output.WriteAll(table.csvRecords())
+	}
+	if err != nil {
+		logging.Warn(request.Context(), "Error sending response", "error", err)
+	}
+}
+
+// csvText prevents spreadsheets from interpreting text as formula, values
+// starting with formula characters are prefixed by apostrophe
+func csvText(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+// csvTexts converts all values by csvText
+func csvTexts(values ...string) []string {
+	for i, value := range values {
+		values[i] = csvText(value)
+	}
+	return values
+}
+
+func (c ListClustersDynContent) jsonExport() listExport {
+	return listExport{Items: c.matching, Page: c.Page}
+}
+
+func (c ListClustersDynContent) csvHeader() []string {
+	return []string{"id", "name"}
+}
+
+func (c ListClustersDynContent) csvRecords() [][]string {
+	records := make([][]string, 0, len(c.matching))
+	for _, cluster := range c.matching {
+		records = append(records, append([]string{strconv.Itoa(cluster.ID)}, csvTexts(cluster.Name)...))
+	}
+	return records
+}
+
+func (c ListProfilesDynContent) jsonExport() listExport {
+	return listExport{Items: c.matching, Page: c.Page}
+}
+
+func (c ListProfilesDynContent) csvHeader() []string {
+	return []string{"id", "description", "changed_at", "changed_by", "configuration"}
+}
+
+func (c ListProfilesDynContent) csvRecords() [][]string {
+	records := make([][]string, 0, len(c.matching))
+	for _, profile := range c.matching {
+		records = append(records, append([]string{strconv.Itoa(profile.ID)},
+			csvTexts(profile.Description, profile.ChangedAt, profile.ChangedBy, profile.Configuration)...))
+	}
+	return records
+}
+
+func (c ListConfigurationsDynContent) jsonExport() listExport {
+	return listExport{Items: c.matching, Page: c.Page}
+}
+
+func (c ListConfigurationsDynContent) csvHeader() []string {
+	return []string{"id", "cluster", "configuration", "changed_at", "changed_by", "active", "reason"}
+}
+
+func (c ListConfigurationsDynContent) csvRecords() [][]string {
+	records := make([][]string, 0, len(c.matching))
+	for _, configuration := range c.matching {
+		records = append(records, append([]string{strconv.Itoa(configuration.ID)},
+			csvTexts(configuration.Cluster, configuration.Configuration, configuration.ChangedAt,
+				configuration.ChangedBy, configuration.Active, configuration.Reason)...))
+	}
+	return records
+}
+
+func (c ListTriggersDynContent) jsonExport() listExport {
+	return listExport{Items: c.matching, Page: c.Page}
+}
+
+func (c ListTriggersDynContent) csvHeader() []string {
+	return []string{"id", "type", "cluster", "reason", "link", "triggered_at", "triggered_by", "acked_at", "parameters", "active"}
+}
+
+func (c ListTriggersDynContent) csvRecords() [][]string {
+	records := make([][]string, 0, len(c.matching))
+	for _, trigger := range c.matching {
+		records = append(records, triggerRecord(trigger))
+	}
+	return records
+}
+
+// triggerRecord converts trigger to CSV record
+func triggerRecord(trigger types.Trigger) []string {
+	record := []string{strconv.Itoa(trigger.ID)}
+	record = append(record, csvTexts(trigger.Type, trigger.Cluster, trigger.Reason, trigger.Link,
+		trigger.TriggeredAt, trigger.TriggeredBy, trigger.AckedAt, trigger.Parameters)...)
+	return append(record, strconv.Itoa(trigger.Active))
+}
@@ -0,0 +1,81 @@
+/*
+Copyright © 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"github.com/tisnik/insights-operator-web-ui/listing"
+	"github.com/tisnik/insights-operator-web-ui/types"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+// TestRenderTableExportsAllItems checks that JSON and CSV exports contain
+// all items matching the query, not just the current page
+func TestRenderTableExportsAllItems(t *testing.T) {
+	count := 2*listing.DefaultPageSize + 1
+	clusters := make([]types.Cluster, count)
+	for i := range clusters {
+		clusters[i] = types.Cluster{ID: i + 1, Name: "cluster-" + strconv.Itoa(i+1)}
+	}
+
+	for _, format := range []string{formatJSON, formatCSV} {
+		t.Run(format, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, "/list-clusters?page=2&format="+format, nil)
+			recorder := httptest.NewRecorder()
+
+			dynData := newListClustersDynContent(request, clusters)
+			if len(dynData.Items) != listing.DefaultPageSize {
+				t.Fatalf("expected %d items on the page, got %d", listing.DefaultPageSize, len(dynData.Items))
+			}
+			if !renderTable(recorder, request, http.StatusOK, "list_clusters.html", dynData) {
+				t.Fatal("table should not be rendered as HTML")
+			}
+
+			if exported := exportedItems(t, format, recorder); exported != count {
+				t.Errorf("expected %d exported items, got %d", count, exported)
+			}
+		})
+	}
+}
+
+// exportedItems returns number of items in JSON or CSV response
+func exportedItems(t *testing.T, format string, recorder *httptest.ResponseRecorder) int {
+	t.Helper()
+	if format == formatCSV {
+		records, err := csv.NewReader(recorder.Body).ReadAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(records) - 1
+	}
+
+	var export struct {
+		Items []types.Cluster `json:"items"`
+		Page  listing.Page    `json:"page"`
+	}
+	if err := json.NewDecoder(recorder.Body).Decode(&export); err != nil {
+		t.Fatal(err)
+	}
+	if export.Page.Total != len(export.Items) {
+		t.Errorf("total %d does not match number of items %d", export.Page.Total, len(export.Items))
+	}
+	return len(export.Items)
+}
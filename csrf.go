@@ -0,0 +1,119 @@
+/*
+Copyright © 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"github.com/tisnik/insights-operator-web-ui/auth"
+	"github.com/tisnik/insights-operator-web-ui/logging"
+	"html/template"
+	"net/http"
+)
+
+// CSRF token is sent in hidden form field or in HTTP header
+const (
+	csrfParameter = "csrf_token"
+	csrfHeader    = "X-CSRF-Token"
+)
+
+// name of cookie with CSRF token used when user login is disabled
+const csrfCookieName = "insights_web_ui_csrf"
+
+// csrfContextKey is used to store CSRF token in request context
+type csrfContextKey struct{}
+
+// isSafeMethod checks whether HTTP method does not change any state, so it
+// does not need to be protected by CSRF token
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+// csrfToken returns CSRF token expected for the request. Token stored in
+// session of the logged in user is used, otherwise token is taken from cookie
+// (double submit cookie). New token is generated when there is no cookie yet.
+func csrfToken(writer http.ResponseWriter, request *http.Request) (token string, generated bool, err error) {
+	if session := currentSession(request); session != nil {
+		return session.CSRFToken, false, nil
+	}
+
+	cookie, err := request.Cookie(csrfCookieName)
+	if err == nil && cookie.Value != "" {
+		return cookie.Value, false, nil
+	}
+
+	token, err = auth.RandomToken()
+	if err != nil {
+		return "", false, err
+	}
+	http.SetCookie(writer, newCookie(csrfCookieName, token, 0))
+	return token, true, nil
+}
+
+// submittedCSRFToken returns token sent with the request
+func submittedCSRFToken(request *http.Request) string {
+	token := request.Header.Get(csrfHeader)
+	if token == "" {
+		token = request.PostFormValue(csrfParameter)
+	}
+	return token
+}
+
+// verifyCSRF rejects state changing requests without valid CSRF token,
+// requests authenticated by bearer token don't need it. The expected token
+// is stored in request context, so it can be added to forms.
+func verifyCSRF(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		token, generated, err := csrfToken(writer, request)
+		if err != nil {
+			logging.Error(request.Context(), "Unable to generate CSRF token", "error", err)
+			http.Error(writer, "Unable to generate CSRF token", http.StatusInternalServerError)
+			return
+		}
+
+		if !isSafeMethod(request.Method) && !bearerAuthenticated(request) {
+			submitted := submittedCSRFToken(request)
+			if generated || subtle.ConstantTimeCompare([]byte(submitted), []byte(token)) != 1 {
+				logging.Warn(request.Context(), "Invalid or missing CSRF token", "method", request.Method, "path", request.URL.Path)
+				errorPageResponse(writer, request, http.StatusForbidden, errorPageTemplate, ErrorPageDynContent{
+					Title:     "Invalid form",
+					Operation: request.Method + " " + request.URL.Path,
+					Message:   "The form has expired or was not sent from this site, please reload the page and try again",
+					RetryURL:  retryURL(request),
+				})
+				return
+			}
+		}
+
+		ctx := context.WithValue(request.Context(), csrfContextKey{}, token)
+		next.ServeHTTP(writer, request.WithContext(ctx))
+	})
+}
+
+// csrfField returns hidden form field with CSRF token expected for the
+// request
+func csrfField(request *http.Request) template.HTML {
+	token := ""
+	if request != nil {
+		token, _ = request.Context().Value(csrfContextKey{}).(string)
+	}
+	return template.HTML(`<input type="hidden" name="` + csrfParameter + `" value="` + template.HTMLEscapeString(token) + `" />`) // #nosec G203
+}
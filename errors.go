@@ -0,0 +1,181 @@
+/*
+Copyright © 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"github.com/tisnik/insights-operator-web-ui/client"
+	"github.com/tisnik/insights-operator-web-ui/logging"
+	"net/http"
+	"net/url"
+)
+
+// templates used to render error pages
+const (
+	errorPageTemplate         = "error.html"
+	notAuthorizedPageTemplate = "not_authorized.html"
+)
+
+// human readable descriptions of operations displayed on error page
+var operationDescriptions = map[string]string{
+	client.OperationListClusters:                "Reading list of clusters",
+	client.OperationListConfigurationProfiles:   "Reading list of configuration profiles",
+	client.OperationGetConfigurationProfile:     "Reading configuration profile",
+	client.OperationCreateConfigurationProfile:  "Creating configuration profile",
+	client.OperationChangeConfigurationProfile:  "Changing configuration profile",
+	client.OperationDeleteConfigurationProfile:  "Deleting configuration profile",
+	client.OperationListClusterConfigurations:   "Reading list of cluster configurations",
+	client.OperationCreateClusterConfiguration:  "Creating cluster configuration",
+	client.OperationEnableClusterConfiguration:  "Enabling cluster configuration",
+	client.OperationDisableClusterConfiguration: "Disabling cluster configuration",
+	client.OperationListTriggers:                "Reading list of triggers",
+	client.OperationListClusterTriggers:         "Reading list of triggers for cluster",
+	client.OperationActivateTrigger:             "Activating trigger",
+	client.OperationDeactivateTrigger:           "Deactivating trigger",
+	client.OperationTriggerMustGather:           "Triggering must-gather",
+}
+
+// ErrorPageDynContent represents dynamic part of HTML page with error
+// description.
+//
+//	Title: short description of the error
+//	Operation: operation that failed
+//	Message: error message
+//	ControllerStatus: HTTP status returned by the controller, 0 if unknown
+//	RetryURL: URL that can be used to repeat the operation
+type ErrorPageDynContent struct {
+	Title            string `json:"title"`
+	Operation        string `json:"operation,omitempty"`
+	Message          string `json:"message,omitempty"`
+	ControllerStatus int    `json:"controller_status,omitempty"`
+	RetryURL         string `json:"-"`
+}
+
+// ControllerStatusText returns textual representation of HTTP status code
+// returned by the controller
+func (c ErrorPageDynContent) ControllerStatusText() string {
+	return http.StatusText(c.ControllerStatus)
+}
+
+// describeOperation returns human readable description of operation
+func describeOperation(operation string) string {
+	description, found := operationDescriptions[operation]
+	if !found {
+		return operation
+	}
+	return description
+}
+
+// retryURL returns URL that repeats the operation. Forms are not re-sent,
+// the user is navigated back to the page with form instead. The page is
+// taken from Referer header only when it points to this site, otherwise
+// the main page is used.
+func retryURL(request *http.Request) string {
+	if request.Method == http.MethodGet {
+		return request.URL.RequestURI()
+	}
+	referer, err := url.Parse(request.Referer())
+	if err != nil || referer.Host == "" || referer.Host != request.Host {
+		return "/"
+	}
+	return safeReturnURL(referer.RequestURI())
+}
+
+// classifyControllerError finds HTTP status that needs to be returned to the
+// UI client and short description of the error
+func classifyControllerError(err error) (status int, controllerStatus int, title string) {
+	var statusError *client.StatusError
+
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout, 0, "Controller timed out"
+	case errors.Is(err, client.ErrControllerUnavailable):
+		return http.StatusServiceUnavailable, 0, "Controller is unavailable"
+	case errors.As(err, &statusError) && client.IsAuthorizationError(err):
+		return http.StatusBadGateway, statusError.StatusCode, "Not authorized by controller"
+	case errors.As(err, &statusError) && statusError.StatusCode == http.StatusNotFound:
+		return http.StatusNotFound, statusError.StatusCode, "Not found"
+	case errors.As(err, &statusError):
+		return http.StatusBadGateway, statusError.StatusCode, "Controller returned an error"
+	default:
+		return http.StatusBadGateway, 0, "Error communicating with the controller"
+	}
+}
+
+// isControllerUnavailable checks whether the controller call failed because
+// the controller can't be reached, i.e. the call might succeed when retried
+func isControllerUnavailable(err error) bool {
+	return errors.Is(err, context.DeadlineExceeded) || errors.Is(err, client.ErrControllerUnavailable)
+}
+
+// needsErrorPage checks whether failed write operation needs to be reported
+// on error page instead of the generic "not created" page, i.e. when the
+// controller can't be reached or refused the credentials
+func needsErrorPage(err error) bool {
+	return isControllerUnavailable(err) || client.IsAuthorizationError(err)
+}
+
+// withStaleMarker lets handlers find out whether data read from the
+// controller have been served from cache while the controller is down
+func withStaleMarker(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		next.ServeHTTP(writer, request.WithContext(client.WithStaleMarker(request.Context())))
+	})
+}
+
+// controllerErrorResponse renders error page for failed controller call with
+// the proper HTTP status code set
+func controllerErrorResponse(writer http.ResponseWriter, request *http.Request, operation string, err error) {
+	logging.Error(request.Context(), errorCommunicatingWithServiceMessage, "operation", describeOperation(operation), "error", err)
+
+	// nobody is waiting for the response
+	if errors.Is(err, context.Canceled) {
+		return
+	}
+
+	page := errorPageTemplate
+	if client.IsAuthorizationError(err) {
+		page = notAuthorizedPageTemplate
+	}
+
+	status, controllerStatus, title := classifyControllerError(err)
+	errorPageResponse(writer, request, status, page, ErrorPageDynContent{
+		Title:            title,
+		Operation:        describeOperation(operation),
+		Message:          err.Error(),
+		ControllerStatus: controllerStatus,
+		RetryURL:         retryURL(request),
+	})
+}
+
+// errorPageResponse renders given error page with HTTP status code, plain
+// text with error title is sent when the page can't be rendered. Requests
+// sent to JSON API get the error description as JSON.
+func errorPageResponse(writer http.ResponseWriter, request *http.Request, status int, page string, dynData ErrorPageDynContent) {
+	if isAPIRequest(request) {
+		writeJSON(writer, request, status, dynData)
+		return
+	}
+
+	err := templates.Render(writer, request, status, page, dynData)
+	if err != nil {
+		logging.Error(request.Context(), errorExecutingTemplate, "page", page, "error", err)
+		writer.WriteHeader(status)
+		writeResponse(writer, dynData.Title)
+	}
+}
@@ -0,0 +1,96 @@
+/*
+Copyright © 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestRetryURL checks that forms are retried from the page they were sent
+// from and that Referer pointing to another site is not used
+func TestRetryURL(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   string
+		target   string
+		referer  string
+		expected string
+	}{
+		{
+			name:     "GET request is repeated",
+			method:   http.MethodGet,
+			target:   "/list-triggers?page=2",
+			referer:  "https://evil.example.com/",
+			expected: "/list-triggers?page=2",
+		},
+		{
+			name:     "form page on the same site",
+			method:   http.MethodPost,
+			target:   "/trigger-must-gather",
+			referer:  "http://webui.example.com/trigger-must-gather-configuration?clusterID=1&clusterName=c1",
+			expected: "/trigger-must-gather-configuration?clusterID=1&clusterName=c1",
+		},
+		{
+			name:     "other site",
+			method:   http.MethodPost,
+			target:   "/trigger-must-gather",
+			referer:  "https://evil.example.com/phishing",
+			expected: "/",
+		},
+		{
+			name:     "other port",
+			method:   http.MethodPost,
+			target:   "/trigger-must-gather",
+			referer:  "http://webui.example.com:8080/list-clusters",
+			expected: "/",
+		},
+		{
+			name:     "protocol relative path",
+			method:   http.MethodPost,
+			target:   "/trigger-must-gather",
+			referer:  "http://webui.example.com//evil.example.com/",
+			expected: "/",
+		},
+		{
+			name:     "relative referer",
+			method:   http.MethodPost,
+			target:   "/trigger-must-gather",
+			referer:  "/list-clusters",
+			expected: "/",
+		},
+		{
+			name:     "no referer",
+			method:   http.MethodPost,
+			target:   "/trigger-must-gather",
+			expected: "/",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := httptest.NewRequest(tt.method, "http://webui.example.com"+tt.target, nil)
+			if tt.referer != "" {
+				request.Header.Set("Referer", tt.referer)
+			}
+			if retry := retryURL(request); retry != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, retry)
+			}
+		})
+	}
+}
@@ -2,4 +2,4 @@ module github.com/tisnik/insights-operator-web-ui
 
 require github.com/spf13/viper v1.4.0
 
-go 1.14
+go 1.16
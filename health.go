@@ -0,0 +1,177 @@
+/*
+Copyright © 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/tisnik/insights-operator-web-ui/logging"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Endpoints used by liveness and readiness probes
+const (
+	healthzEndpoint = "/healthz"
+	readyzEndpoint  = "/readyz"
+)
+
+// Results of health checks
+const (
+	healthOK      = "ok"
+	healthFailing = "failing"
+)
+
+// DefaultReadinessCacheDuration is used when no cache duration is configured
+const DefaultReadinessCacheDuration = 10 * time.Second
+
+// HealthConfiguration represents configuration of health checks.
+//
+//	ReadinessCacheDuration: how long the result of controller check is reused
+type HealthConfiguration struct {
+	ReadinessCacheDuration time.Duration
+}
+
+// HealthCheck is result of one check.
+//
+//	Status: ok or failing
+//	Message: details, for example error returned by the controller
+//	CheckedAt: time the check has been performed, empty for checks that
+//	    are not cached
+//	Cached: the result of previous check has been reused
+type HealthCheck struct {
+	Status    string `json:"status"`
+	Message   string `json:"message,omitempty"`
+	CheckedAt string `json:"checked_at,omitempty"`
+	Cached    bool   `json:"cached,omitempty"`
+}
+
+// HealthReport is returned by health endpoints, the status is ok only when
+// all checks pass.
+//
+//	Status: ok or failing
+//	Checks: results of individual checks by name
+type HealthReport struct {
+	Status string                 `json:"status"`
+	Checks map[string]HealthCheck `json:"checks"`
+}
+
+// controllerCheck caches result of the last check of controller, so frequent
+// probes do not overload it
+type controllerCheck struct {
+	mutex     sync.Mutex
+	duration  time.Duration
+	checkedAt time.Time
+	result    HealthCheck
+}
+
+// readiness is used by readiness endpoint
+var readiness = &controllerCheck{duration: DefaultReadinessCacheDuration}
+
+// initHealth sets how long the result of controller check is cached
+func initHealth(configuration HealthConfiguration) {
+	if configuration.ReadinessCacheDuration > 0 {
+		readiness.duration = configuration.ReadinessCacheDuration
+	}
+}
+
+// check returns cached result or calls the controller when the cached one
+// is too old. Results of calls canceled by the client are not cached.
+func (c *controllerCheck) check(ctx context.Context) HealthCheck {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if !c.checkedAt.IsZero() && time.Since(c.checkedAt) < c.duration {
+		result := c.result
+		result.Cached = true
+		return result
+	}
+
+	checkedAt := time.Now()
+	result := HealthCheck{Status: healthOK, CheckedAt: checkedAt.UTC().Format(time.RFC3339)}
+	err := controller.Ping(ctx)
+	if err != nil {
+		result.Status = healthFailing
+		result.Message = err.Error()
+	}
+	if ctx.Err() != nil {
+		return result
+	}
+
+	if result.Status != c.result.Status {
+		if err != nil {
+			logging.Warn(ctx, "Controller is not reachable", "error", err)
+		} else {
+			logging.Info(ctx, "Controller is reachable")
+		}
+	}
+	c.checkedAt = checkedAt
+	c.result = result
+	return result
+}
+
+// templatesCheck checks that page templates have been parsed
+func templatesCheck() HealthCheck {
+	count := 0
+	if templates != nil {
+		count = templates.Count()
+	}
+	if count == 0 {
+		return HealthCheck{Status: healthFailing, Message: "no templates parsed"}
+	}
+	return HealthCheck{Status: healthOK, Message: strconv.Itoa(count) + " templates parsed"}
+}
+
+// writeHealthReport writes the report as JSON, with 503 status when any
+// check fails
+func writeHealthReport(writer http.ResponseWriter, request *http.Request, checks map[string]HealthCheck) {
+	report := HealthReport{Status: healthOK, Checks: checks}
+	status := http.StatusOK
+	for _, check := range checks {
+		if check.Status != healthOK {
+			report.Status = healthFailing
+			status = http.StatusServiceUnavailable
+		}
+	}
+
+	writer.Header().Set("Content-Type", ContentTypeJSON)
+	writer.Header().Set("Cache-Control", "no-store")
+	writer.WriteHeader(status)
+	err := json.NewEncoder(writer).Encode(report)
+	if err != nil {
+		logging.Warn(request.Context(), "Unable to write health report", "error", err)
+	}
+}
+
+// healthz is used by liveness probe, it checks only state of the process
+// itself and never calls the controller
+func healthz(writer http.ResponseWriter, request *http.Request) {
+	writeHealthReport(writer, request, map[string]HealthCheck{
+		"templates": templatesCheck(),
+	})
+}
+
+// readyz is used by readiness probe, the service is ready when the
+// controller is reachable
+func readyz(writer http.ResponseWriter, request *http.Request) {
+	writeHealthReport(writer, request, map[string]HealthCheck{
+		"templates":  templatesCheck(),
+		"controller": readiness.check(request.Context()),
+	})
+}
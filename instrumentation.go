@@ -0,0 +1,78 @@
+/*
+Copyright © 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"github.com/tisnik/insights-operator-web-ui/client"
+	"github.com/tisnik/insights-operator-web-ui/metrics"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// metricsEndpoint exposes metrics in Prometheus text format
+const metricsEndpoint = "/metrics"
+
+// route label used for requests that don't match any route
+const unmatchedRoute = "unmatched"
+
+// Metrics of HTTP requests handled by the UI and of rendered pages
+var (
+	httpRequests = metrics.NewCounterVec(
+		"insights_web_ui_http_requests_total",
+		"Number of HTTP requests by route, method and status.",
+		"route", "method", "status")
+
+	httpRequestDuration = metrics.NewHistogramVec(
+		"insights_web_ui_http_request_duration_seconds",
+		"Duration of HTTP requests by route and method.",
+		nil, "route", "method")
+
+	templateRenderErrors = metrics.NewCounterVec(
+		"insights_web_ui_template_render_errors_total",
+		"Number of pages that could not be rendered by page template.",
+		"page")
+)
+
+// metricsRegistry contains all metrics exposed on the metrics endpoint
+var metricsRegistry = metrics.NewRegistry()
+
+// initMetrics registers metrics of the UI and of calls to the controller,
+// it needs to be called after circuit breaker has been constructed
+func initMetrics(breaker *client.CircuitBreaker) error {
+	err := metricsRegistry.Register(httpRequests, httpRequestDuration, templateRenderErrors)
+	if err != nil {
+		return err
+	}
+	return client.RegisterMetrics(metricsRegistry, breaker)
+}
+
+// instrumentRoute returns middleware that counts requests for the route and
+// measures their duration. Route pattern is used as label instead of the
+// path, so paths with parameters do not create new series.
+func instrumentRoute(pattern string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			recorder := &statusRecorder{ResponseWriter: writer, status: http.StatusOK}
+			started := time.Now()
+			next.ServeHTTP(recorder, request)
+
+			httpRequests.Inc(pattern, request.Method, strconv.Itoa(recorder.status))
+			httpRequestDuration.Observe(time.Since(started).Seconds(), pattern, request.Method)
+		})
+	}
+}
@@ -0,0 +1,154 @@
+/*
+Copyright © 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"github.com/tisnik/insights-operator-web-ui/listing"
+	"github.com/tisnik/insights-operator-web-ui/types"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// Columns of list pages, names are used in query parameters for sorting
+// and filtering. Names are the same as JSON names of item fields, values of
+// columns are read from these fields.
+var (
+	clusterListSchema = listing.Schema{
+		Columns: []listing.Column{
+			{Name: "id", Numeric: true},
+			{Name: "name"},
+		},
+	}
+
+	profileListSchema = listing.Schema{
+		Columns: []listing.Column{
+			{Name: "id", Numeric: true},
+			{Name: "description"},
+			{Name: "changed_at"},
+			{Name: "changed_by", Filter: true},
+			{Name: "configuration"},
+		},
+		DateColumn: "changed_at",
+	}
+
+	configurationListSchema = listing.Schema{
+		Columns: []listing.Column{
+			{Name: "id", Numeric: true},
+			{Name: "cluster", Numeric: true, Filter: true},
+			{Name: "configuration", Numeric: true},
+			{Name: "changed_at"},
+			{Name: "changed_by", Filter: true},
+			{Name: "active", Filter: true},
+			{Name: "reason"},
+		},
+		DateColumn: "changed_at",
+	}
+
+	triggerListSchema = listing.Schema{
+		Columns: []listing.Column{
+			{Name: "id", Numeric: true},
+			{Name: "type", Filter: true},
+			{Name: "cluster", Filter: true},
+			{Name: "reason"},
+			{Name: "link"},
+			{Name: "triggered_at"},
+			{Name: "triggered_by", Filter: true},
+			{Name: "acked_at"},
+			{Name: "active", Numeric: true, Filter: true},
+		},
+		DateColumn: "triggered_at",
+	}
+)
+
+// selectListItems selects items matching query sent in request, all
+// matching items are stored to slice matching points to
+func selectListItems(request *http.Request, schema listing.Schema, items interface{}, matching interface{}) listing.Page {
+	query := schema.ParseQuery(request.URL.Query())
+	return schema.Select(query, items, matching)
+}
+
+// newListClustersDynContent selects clusters matching query sent in request
+func newListClustersDynContent(request *http.Request, clusters []types.Cluster) ListClustersDynContent {
+	var matching []types.Cluster
+	page := selectListItems(request, clusterListSchema, clusters, &matching)
+	return ListClustersDynContent{Items: matching[page.Start:page.End], Page: page, matching: matching}
+}
+
+// newListProfilesDynContent selects profiles matching query sent in request
+func newListProfilesDynContent(request *http.Request, profiles []types.ConfigurationProfile) ListProfilesDynContent {
+	var matching []types.ConfigurationProfile
+	page := selectListItems(request, profileListSchema, profiles, &matching)
+	return ListProfilesDynContent{Items: matching[page.Start:page.End], Page: page, matching: matching}
+}
+
+// newListConfigurationsDynContent selects cluster configurations matching
+// query sent in request
+func newListConfigurationsDynContent(request *http.Request, configurations []types.ClusterConfiguration) ListConfigurationsDynContent {
+	var matching []types.ClusterConfiguration
+	page := selectListItems(request, configurationListSchema, configurations, &matching)
+	return ListConfigurationsDynContent{Items: matching[page.Start:page.End], Page: page, matching: matching}
+}
+
+// newListTriggersDynContent selects triggers matching query sent in request
+func newListTriggersDynContent(request *http.Request, triggers []types.Trigger) ListTriggersDynContent {
+	var matching []types.Trigger
+	page := selectListItems(request, triggerListSchema, triggers, &matching)
+	return ListTriggersDynContent{Items: matching[page.Start:page.End], Page: page, matching: matching}
+}
+
+// pageSizes are page sizes user can select
+var pageSizes = []int{10, 25, listing.DefaultPageSize, 100, listing.MaxPageSize}
+
+// listURL returns address of the current page with query parameters changed
+// by the callback
+func listURL(request *http.Request, change func(query url.Values)) string {
+	if request == nil {
+		return ""
+	}
+	query := request.URL.Query()
+	change(query)
+	return request.URL.Path + "?" + query.Encode()
+}
+
+// pageURL returns address of given page of the current list
+func pageURL(request *http.Request, page int) string {
+	return listURL(request, func(query url.Values) {
+		query.Set(listing.PageParameter, strconv.Itoa(page))
+	})
+}
+
+// sortHeader returns header of list column with link that sorts the list by
+// the column. Clicking the column the list is sorted by reverses the order.
+func sortHeader(request *http.Request, column string, label string) template.HTML {
+	if request == nil {
+		return template.HTML(template.HTMLEscapeString(label)) // #nosec G203
+	}
+	order, mark := listing.SortLink(request.URL.Query(), column)
+	if mark != "" {
+		mark = " " + mark
+	}
+
+	address := listURL(request, func(query url.Values) {
+		query.Set(listing.SortParameter, column)
+		query.Set(listing.OrderParameter, order)
+		query.Del(listing.PageParameter)
+	})
+	return template.HTML(`<a href="` + template.HTMLEscapeString(address) + `">` + // #nosec G203
+		template.HTMLEscapeString(label) + `</a>` + mark)
+}
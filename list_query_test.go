@@ -0,0 +1,77 @@
+/*
+Copyright © 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"github.com/tisnik/insights-operator-web-ui/types"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestNewListConfigurationsDynContent checks that configurations are
+// filtered by exact cluster ID and sorted by cluster ID as numbers
+func TestNewListConfigurationsDynContent(t *testing.T) {
+	configurations := []types.ClusterConfiguration{
+		{ID: 1, Cluster: "10"},
+		{ID: 2, Cluster: "2"},
+		{ID: 3, Cluster: "1"},
+		{ID: 4, Cluster: "2"},
+	}
+
+	tests := []struct {
+		name        string
+		query       string
+		expectedIDs []int
+	}{
+		{
+			name:        "filter by cluster ID",
+			query:       "cluster=1",
+			expectedIDs: []int{3},
+		},
+		{
+			name:        "sort by cluster ID",
+			query:       "sort=cluster",
+			expectedIDs: []int{3, 2, 4, 1},
+		},
+		{
+			name:        "sort by cluster ID descending",
+			query:       "sort=cluster&order=desc",
+			expectedIDs: []int{1, 2, 4, 3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, "/list-configurations?"+tt.query, nil)
+			dynData := newListConfigurationsDynContent(request, configurations)
+
+			ids := make([]int, len(dynData.Items))
+			for i, configuration := range dynData.Items {
+				ids[i] = configuration.ID
+			}
+			if len(ids) != len(tt.expectedIDs) {
+				t.Fatalf("expected configurations %v, got %v", tt.expectedIDs, ids)
+			}
+			for i := range ids {
+				if ids[i] != tt.expectedIDs[i] {
+					t.Fatalf("expected configurations %v, got %v", tt.expectedIDs, ids)
+				}
+			}
+		})
+	}
+}
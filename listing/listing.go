@@ -0,0 +1,370 @@
+/*
+Copyright © 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package listing implements search, filtering, sorting and pagination of
+// lists displayed by the UI. Items are accessed by callback that returns
+// value of given column, so the same code works for all types of items.
+// Lists of structs can be selected by Select, it reads values of columns
+// from struct fields.
+package listing
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Page sizes used when no or too large size is requested
+const (
+	DefaultPageSize = 50
+	MaxPageSize     = 500
+)
+
+// Query parameters read by ParseQuery, column filters use names of columns
+const (
+	SearchParameter   = "q"
+	FromParameter     = "from"
+	ToParameter       = "to"
+	SortParameter     = "sort"
+	OrderParameter    = "order"
+	PageParameter     = "page"
+	PageSizeParameter = "page_size"
+)
+
+// Sort orders
+const (
+	OrderAscending  = "asc"
+	OrderDescending = "desc"
+)
+
+// DateLayout is format of date range boundaries, dates in lists are
+// compared by their first ten characters
+const DateLayout = "2006-01-02"
+
+// Column describes one column of list.
+//
+//	Name: name used in query parameters
+//	Numeric: values are compared as numbers when sorting
+//	Filter: items can be filtered by exact value of the column
+type Column struct {
+	Name    string
+	Numeric bool
+	Filter  bool
+}
+
+// Schema describes columns of list and which of them can be used for date
+// range.
+//
+//	Columns: all columns, free text search looks into all of them
+//	DateColumn: column date range applies to, empty when not supported
+type Schema struct {
+	Columns    []Column
+	DateColumn string
+}
+
+// Query represents search, filters, sort order and page requested by user.
+//
+//	Search: text all matching items contain in any column
+//	Filters: exact values of columns by column name
+//	From: the first day of date range, empty when not limited
+//	To: the last day of date range, empty when not limited
+//	Sort: column the items are sorted by, original order is kept when empty
+//	Order: asc or desc
+//	Page: number of page, the first page has number 1
+//	PageSize: maximal number of items on page
+type Query struct {
+	Search   string            `json:"q,omitempty"`
+	Filters  map[string]string `json:"filters,omitempty"`
+	From     string            `json:"from,omitempty"`
+	To       string            `json:"to,omitempty"`
+	Sort     string            `json:"sort,omitempty"`
+	Order    string            `json:"order"`
+	Page     int               `json:"page"`
+	PageSize int               `json:"page_size"`
+}
+
+// Page describes the page of items that is displayed.
+//
+//	Query: query the page has been selected by
+//	Total: number of items matching search and filters
+//	Pages: number of pages
+//	Start: index of the first item on page among matching items
+//	End: index after the last item on page
+type Page struct {
+	Query Query `json:"query"`
+	Total int   `json:"total"`
+	Pages int   `json:"pages"`
+	Start int   `json:"-"`
+	End   int   `json:"-"`
+}
+
+// column returns description of column with given name
+func (s Schema) column(name string) (Column, bool) {
+	for _, column := range s.Columns {
+		if column.Name == name {
+			return column, true
+		}
+	}
+	return Column{}, false
+}
+
+// ParseQuery reads query from URL parameters. Invalid values (unknown
+// columns, malformed dates and numbers) are ignored, so a broken link still
+// displays the list.
+func (s Schema) ParseQuery(values url.Values) Query {
+	query := Query{
+		Search:   strings.TrimSpace(values.Get(SearchParameter)),
+		Filters:  map[string]string{},
+		Order:    OrderAscending,
+		Page:     positiveInt(values.Get(PageParameter), 1),
+		PageSize: positiveInt(values.Get(PageSizeParameter), DefaultPageSize),
+	}
+	if query.PageSize > MaxPageSize {
+		query.PageSize = MaxPageSize
+	}
+
+	for _, column := range s.Columns {
+		value := strings.TrimSpace(values.Get(column.Name))
+		if column.Filter && value != "" {
+			query.Filters[column.Name] = value
+		}
+	}
+
+	if s.DateColumn != "" {
+		query.From = validDate(values.Get(FromParameter))
+		query.To = validDate(values.Get(ToParameter))
+	}
+
+	if _, found := s.column(values.Get(SortParameter)); found {
+		query.Sort = values.Get(SortParameter)
+	}
+	if values.Get(OrderParameter) == OrderDescending {
+		query.Order = OrderDescending
+	}
+	return query
+}
+
+// positiveInt converts text to positive number, default value is returned
+// for anything else
+func positiveInt(text string, defaultValue int) int {
+	value, err := strconv.Atoi(text)
+	if err != nil || value <= 0 {
+		return defaultValue
+	}
+	return value
+}
+
+// validDate returns the date when it is in DateLayout format, empty string
+// otherwise
+func validDate(date string) string {
+	_, err := time.Parse(DateLayout, date)
+	if err != nil {
+		return ""
+	}
+	return date
+}
+
+// Apply selects items matching the query and sorts them. Indices of all
+// matching items are returned in sorted order together with the page that
+// needs to be displayed, value returns value of given column of i-th item.
+func (s Schema) Apply(query Query, count int, value func(i int, column string) string) ([]int, Page) {
+	matching := make([]int, 0, count)
+	for i := 0; i < count; i++ {
+		if s.matches(query, i, value) {
+			matching = append(matching, i)
+		}
+	}
+
+	if column, found := s.column(query.Sort); found {
+		sort.SliceStable(matching, func(a, b int) bool {
+			less := compare(column, value(matching[a], column.Name), value(matching[b], column.Name))
+			if query.Order == OrderDescending {
+				return less > 0
+			}
+			return less < 0
+		})
+	}
+
+	return matching, newPage(query, len(matching))
+}
+
+// Select selects items matching the query and sorts them like Apply.
+// Items need to be slice of structs, value of column is read from the field
+// with JSON tag equal to the column name. All matching items are stored to
+// slice matching points to, it needs to be of the same type as items.
+func (s Schema) Select(query Query, items interface{}, matching interface{}) Page {
+	list := reflect.ValueOf(items)
+	fields := fieldIndices(list.Type().Elem())
+
+	indices, page := s.Apply(query, list.Len(), func(i int, column string) string {
+		index, found := fields[column]
+		if !found {
+			return ""
+		}
+		return fieldValue(list.Index(i).Field(index))
+	})
+
+	selected := reflect.MakeSlice(list.Type(), 0, len(indices))
+	for _, i := range indices {
+		selected = reflect.Append(selected, list.Index(i))
+	}
+	reflect.ValueOf(matching).Elem().Set(selected)
+	return page
+}
+
+// fieldIndices returns indices of struct fields by their JSON names
+func fieldIndices(structType reflect.Type) map[string]int {
+	fields := make(map[string]int, structType.NumField())
+	for i := 0; i < structType.NumField(); i++ {
+		name := strings.Split(structType.Field(i).Tag.Get("json"), ",")[0]
+		if name != "" && name != "-" {
+			fields[name] = i
+		}
+	}
+	return fields
+}
+
+// fieldValue formats value of struct field
+func fieldValue(field reflect.Value) string {
+	switch field.Kind() {
+	case reflect.String:
+		return field.String()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(field.Int(), 10)
+	}
+	return fmt.Sprint(field.Interface())
+}
+
+// matches checks whether i-th item contains searched text, has values
+// required by filters and is in the date range
+func (s Schema) matches(query Query, i int, value func(i int, column string) string) bool {
+	for name, expected := range query.Filters {
+		if !strings.EqualFold(value(i, name), expected) {
+			return false
+		}
+	}
+
+	if (query.From != "" || query.To != "") && !inDateRange(query, value(i, s.DateColumn)) {
+		return false
+	}
+
+	return query.Search == "" || s.contains(i, value, strings.ToLower(query.Search))
+}
+
+// inDateRange checks whether the date is within date range of the query,
+// only the date part of timestamps is compared
+func inDateRange(query Query, date string) bool {
+	if len(date) < len(DateLayout) {
+		return false
+	}
+	date = date[:len(DateLayout)]
+	return (query.From == "" || date >= query.From) && (query.To == "" || date <= query.To)
+}
+
+// contains checks whether any column of i-th item contains the text
+func (s Schema) contains(i int, value func(i int, column string) string, text string) bool {
+	for _, column := range s.Columns {
+		if strings.Contains(strings.ToLower(value(i, column.Name)), text) {
+			return true
+		}
+	}
+	return false
+}
+
+// compare compares two values of column, it returns negative number when
+// the first value is lower
+func compare(column Column, a string, b string) int {
+	if column.Numeric {
+		x, errX := strconv.ParseFloat(a, 64)
+		y, errY := strconv.ParseFloat(b, 64)
+		if errX == nil && errY == nil {
+			switch {
+			case x < y:
+				return -1
+			case x > y:
+				return 1
+			}
+			return 0
+		}
+	}
+	return strings.Compare(strings.ToLower(a), strings.ToLower(b))
+}
+
+// newPage computes boundaries of requested page, the last page is used when
+// the requested one is after the end of the list
+func newPage(query Query, total int) Page {
+	pages := (total + query.PageSize - 1) / query.PageSize
+	if pages == 0 {
+		pages = 1
+	}
+	if query.Page > pages {
+		query.Page = pages
+	}
+
+	start := (query.Page - 1) * query.PageSize
+	end := start + query.PageSize
+	if end > total {
+		end = total
+	}
+	return Page{Query: query, Total: total, Pages: pages, Start: start, End: end}
+}
+
+// HasPrevious checks whether there is page before this one
+func (p Page) HasPrevious() bool {
+	return p.Query.Page > 1
+}
+
+// HasNext checks whether there is page after this one
+func (p Page) HasNext() bool {
+	return p.Query.Page < p.Pages
+}
+
+// Previous returns number of the previous page
+func (p Page) Previous() int {
+	return p.Query.Page - 1
+}
+
+// Next returns number of the next page
+func (p Page) Next() int {
+	return p.Query.Page + 1
+}
+
+// First returns position of the first item on page counted from one, zero
+// for empty list
+func (p Page) First() int {
+	if p.Total == 0 {
+		return 0
+	}
+	return p.Start + 1
+}
+
+// SortLink returns order the list needs to be sorted in when user selects
+// the column and arrow displayed in header of column the list is sorted by.
+// Selecting the column the list is sorted by in ascending order reverses
+// the order.
+func SortLink(values url.Values, column string) (order string, mark string) {
+	if values.Get(SortParameter) != column {
+		return OrderAscending, ""
+	}
+	if values.Get(OrderParameter) == OrderDescending {
+		return OrderAscending, "▼"
+	}
+	return OrderDescending, "▲"
+}
@@ -0,0 +1,224 @@
+/*
+Copyright © 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package listing
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+// testItem is item of list used by tests
+type testItem struct {
+	ID        int    `json:"id"`
+	Name      string `json:"name"`
+	ChangedAt string `json:"changed_at"`
+	Active    int    `json:"active,omitempty"`
+}
+
+var testSchema = Schema{
+	Columns: []Column{
+		{Name: "id", Numeric: true},
+		{Name: "name"},
+		{Name: "changed_at"},
+		{Name: "active", Numeric: true, Filter: true},
+	},
+	DateColumn: "changed_at",
+}
+
+var testItems = []testItem{
+	{ID: 10, Name: "beta", ChangedAt: "2022-01-15T10:00:00", Active: 1},
+	{ID: 2, Name: "Alpha", ChangedAt: "2022-01-31T23:59:59"},
+	{ID: 33, Name: "gamma", ChangedAt: "2022-02-01T00:00:00", Active: 1},
+	{ID: 4, Name: "delta", ChangedAt: ""},
+	{ID: 5, Name: "alpha", ChangedAt: "2021-12-31"},
+}
+
+// selectIDs selects test items by query given as URL parameters and returns
+// IDs of all matching items together with the page
+func selectIDs(t *testing.T, parameters string) ([]int, Page) {
+	t.Helper()
+	values, err := url.ParseQuery(parameters)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var matching []testItem
+	page := testSchema.Select(testSchema.ParseQuery(values), testItems, &matching)
+	ids := []int{}
+	for _, item := range matching {
+		ids = append(ids, item.ID)
+	}
+	return ids, page
+}
+
+// TestPageClamping checks that page number and size are kept within
+// boundaries of the list
+func TestPageClamping(t *testing.T) {
+	tests := []struct {
+		parameters string
+		page       int
+		pageSize   int
+		pages      int
+		start      int
+		end        int
+	}{
+		{parameters: "", page: 1, pageSize: DefaultPageSize, pages: 1, start: 0, end: 5},
+		{parameters: "page_size=2", page: 1, pageSize: 2, pages: 3, start: 0, end: 2},
+		{parameters: "page_size=2&page=2", page: 2, pageSize: 2, pages: 3, start: 2, end: 4},
+		{parameters: "page_size=2&page=3", page: 3, pageSize: 2, pages: 3, start: 4, end: 5},
+		{parameters: "page_size=2&page=100", page: 3, pageSize: 2, pages: 3, start: 4, end: 5},
+		{parameters: "page_size=2&page=0", page: 1, pageSize: 2, pages: 3, start: 0, end: 2},
+		{parameters: "page_size=2&page=-1", page: 1, pageSize: 2, pages: 3, start: 0, end: 2},
+		{parameters: "page_size=2&page=x", page: 1, pageSize: 2, pages: 3, start: 0, end: 2},
+		{parameters: "page_size=0", page: 1, pageSize: DefaultPageSize, pages: 1, start: 0, end: 5},
+		{parameters: "page_size=100000", page: 1, pageSize: MaxPageSize, pages: 1, start: 0, end: 5},
+		{parameters: "q=nothing&page=5", page: 1, pageSize: DefaultPageSize, pages: 1, start: 0, end: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.parameters, func(t *testing.T) {
+			_, page := selectIDs(t, tt.parameters)
+			if page.Query.Page != tt.page || page.Query.PageSize != tt.pageSize || page.Pages != tt.pages {
+				t.Errorf("expected page %d of %d with size %d, got %d of %d with size %d",
+					tt.page, tt.pages, tt.pageSize, page.Query.Page, page.Pages, page.Query.PageSize)
+			}
+			if page.Start != tt.start || page.End != tt.end {
+				t.Errorf("expected items %d-%d, got %d-%d", tt.start, tt.end, page.Start, page.End)
+			}
+		})
+	}
+}
+
+// TestEmptyPage checks position of items on page of empty list
+func TestEmptyPage(t *testing.T) {
+	_, page := selectIDs(t, "q=nothing")
+	if page.Total != 0 || page.First() != 0 || page.HasPrevious() || page.HasNext() {
+		t.Errorf("unexpected empty page %+v", page)
+	}
+}
+
+// TestDateRange checks that date range includes both boundaries and that
+// timestamps are compared by their date part
+func TestDateRange(t *testing.T) {
+	tests := []struct {
+		parameters string
+		ids        []int
+	}{
+		{parameters: "from=2022-01-15", ids: []int{10, 2, 33}},
+		{parameters: "to=2022-01-31", ids: []int{10, 2, 5}},
+		{parameters: "from=2022-01-15&to=2022-01-31", ids: []int{10, 2}},
+		{parameters: "from=2022-01-31&to=2022-01-31", ids: []int{2}},
+		{parameters: "from=2022-02-02", ids: []int{}},
+		{parameters: "from=2022-01-31&to=2022-01-01", ids: []int{}},
+		{parameters: "from=31.01.2022", ids: []int{10, 2, 33, 4, 5}},
+		{parameters: "from=2022-13-01&to=garbage", ids: []int{10, 2, 33, 4, 5}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.parameters, func(t *testing.T) {
+			ids, _ := selectIDs(t, tt.parameters)
+			if !reflect.DeepEqual(ids, tt.ids) {
+				t.Errorf("expected items %v, got %v", tt.ids, ids)
+			}
+		})
+	}
+}
+
+// TestSortOrder checks sorting by numeric and text columns in both orders
+// and that unknown columns don't change the order
+func TestSortOrder(t *testing.T) {
+	tests := []struct {
+		parameters string
+		sort       string
+		order      string
+		ids        []int
+	}{
+		{parameters: "", order: OrderAscending, ids: []int{10, 2, 33, 4, 5}},
+		{parameters: "sort=id", sort: "id", order: OrderAscending, ids: []int{2, 4, 5, 10, 33}},
+		{parameters: "sort=id&order=desc", sort: "id", order: OrderDescending, ids: []int{33, 10, 5, 4, 2}},
+		{parameters: "sort=name", sort: "name", order: OrderAscending, ids: []int{2, 5, 10, 4, 33}},
+		{parameters: "sort=name&order=desc", sort: "name", order: OrderDescending, ids: []int{33, 4, 10, 2, 5}},
+		{parameters: "sort=changed_at", sort: "changed_at", order: OrderAscending, ids: []int{4, 5, 10, 2, 33}},
+		{parameters: "sort=name&order=random", sort: "name", order: OrderAscending, ids: []int{2, 5, 10, 4, 33}},
+		{parameters: "sort=unknown", order: OrderAscending, ids: []int{10, 2, 33, 4, 5}},
+		{parameters: "sort=unknown&order=desc", order: OrderDescending, ids: []int{10, 2, 33, 4, 5}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.parameters, func(t *testing.T) {
+			ids, page := selectIDs(t, tt.parameters)
+			if page.Query.Sort != tt.sort || page.Query.Order != tt.order {
+				t.Errorf("expected sort by %q %s, got %q %s", tt.sort, tt.order, page.Query.Sort, page.Query.Order)
+			}
+			if !reflect.DeepEqual(ids, tt.ids) {
+				t.Errorf("expected items %v, got %v", tt.ids, ids)
+			}
+		})
+	}
+}
+
+// TestSearchAndFilters checks free text search and column filters
+func TestSearchAndFilters(t *testing.T) {
+	tests := []struct {
+		parameters string
+		ids        []int
+	}{
+		{parameters: "q=ALPHA", ids: []int{2, 5}},
+		{parameters: "q=2022-02", ids: []int{33}},
+		{parameters: "active=1", ids: []int{10, 33}},
+		{parameters: "active=1&q=gam", ids: []int{33}},
+		{parameters: "name=alpha", ids: []int{10, 2, 33, 4, 5}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.parameters, func(t *testing.T) {
+			ids, _ := selectIDs(t, tt.parameters)
+			if !reflect.DeepEqual(ids, tt.ids) {
+				t.Errorf("expected items %v, got %v", tt.ids, ids)
+			}
+		})
+	}
+}
+
+// TestSortLink checks order selected by column header and its mark
+func TestSortLink(t *testing.T) {
+	tests := []struct {
+		parameters string
+		order      string
+		mark       string
+	}{
+		{parameters: "", order: OrderAscending, mark: ""},
+		{parameters: "sort=id", order: OrderDescending, mark: "▲"},
+		{parameters: "sort=id&order=asc", order: OrderDescending, mark: "▲"},
+		{parameters: "sort=id&order=desc", order: OrderAscending, mark: "▼"},
+		{parameters: "sort=name&order=desc", order: OrderAscending, mark: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.parameters, func(t *testing.T) {
+			values, err := url.ParseQuery(tt.parameters)
+			if err != nil {
+				t.Fatal(err)
+			}
+			order, mark := SortLink(values, "id")
+			if order != tt.order || mark != tt.mark {
+				t.Errorf("expected %s %q, got %s %q", tt.order, tt.mark, order, mark)
+			}
+		})
+	}
+}
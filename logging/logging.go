@@ -0,0 +1,295 @@
+/*
+Copyright © 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package logging implements leveled structured logger. Each message is
+// written as one line, either as plain text or as JSON object, together with
+// fields given as key-value pairs and ID of HTTP request the message belongs
+// to.
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Level represents severity of logged message
+type Level int
+
+// Supported levels, messages below the configured level are not written
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+// names of levels used in configuration and in output
+var levelNames = map[Level]string{
+	LevelDebug: "debug",
+	LevelInfo:  "info",
+	LevelWarn:  "warn",
+	LevelError: "error",
+}
+
+// Supported output formats
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// timestamp format used in output
+const timeFormat = "2006-01-02T15:04:05.000Z07:00"
+
+// key used for fields without name when odd number of arguments is passed
+const missingKey = "!BADKEY"
+
+// Configuration represents configuration of the logger.
+//
+//	Level: the lowest level of written messages (debug, info, warn, error)
+//	Format: output format (text or json)
+//	RedactedFields: names of form fields whose values are never logged
+type Configuration struct {
+	Level          string
+	Format         string
+	RedactedFields []string
+}
+
+// logger writes messages to the output
+type logger struct {
+	mutex  sync.Mutex
+	output io.Writer
+	level  Level
+	format string
+}
+
+// std is the logger used by all package functions
+var std = &logger{
+	output: os.Stderr,
+	level:  LevelInfo,
+	format: FormatText,
+}
+
+// String returns name of the level
+func (l Level) String() string {
+	name, found := levelNames[l]
+	if !found {
+		return strconv.Itoa(int(l))
+	}
+	return name
+}
+
+// ParseLevel converts name of the level to Level
+func ParseLevel(name string) (Level, error) {
+	for level, levelName := range levelNames {
+		if strings.EqualFold(name, levelName) {
+			return level, nil
+		}
+	}
+	return LevelInfo, fmt.Errorf("unknown log level %q", name)
+}
+
+// Configure sets level and format of the logger. Defaults (info level, text
+// format) are used for settings that are not specified.
+func Configure(configuration Configuration) error {
+	level := LevelInfo
+	if configuration.Level != "" {
+		var err error
+		level, err = ParseLevel(configuration.Level)
+		if err != nil {
+			return err
+		}
+	}
+
+	format := configuration.Format
+	switch format {
+	case "":
+		format = FormatText
+	case FormatText, FormatJSON:
+	default:
+		return fmt.Errorf("unknown log format %q", format)
+	}
+
+	if configuration.RedactedFields != nil {
+		setRedactedFields(configuration.RedactedFields)
+	}
+
+	std.mutex.Lock()
+	defer std.mutex.Unlock()
+	std.level = level
+	std.format = format
+	return nil
+}
+
+// SetOutput changes where messages are written to
+func SetOutput(output io.Writer) {
+	std.mutex.Lock()
+	defer std.mutex.Unlock()
+	std.output = output
+}
+
+// Debug writes message with debug level
+func Debug(ctx context.Context, message string, fields ...interface{}) {
+	std.log(ctx, LevelDebug, message, fields)
+}
+
+// Info writes message with info level
+func Info(ctx context.Context, message string, fields ...interface{}) {
+	std.log(ctx, LevelInfo, message, fields)
+}
+
+// Warn writes message with warn level
+func Warn(ctx context.Context, message string, fields ...interface{}) {
+	std.log(ctx, LevelWarn, message, fields)
+}
+
+// Error writes message with error level
+func Error(ctx context.Context, message string, fields ...interface{}) {
+	std.log(ctx, LevelError, message, fields)
+}
+
+// Fatal writes message with error level and stops the service
+func Fatal(ctx context.Context, message string, fields ...interface{}) {
+	std.log(ctx, LevelError, message, fields)
+	os.Exit(1)
+}
+
+// Enabled checks whether messages with given level are written
+func Enabled(level Level) bool {
+	std.mutex.Lock()
+	defer std.mutex.Unlock()
+	return level >= std.level
+}
+
+// log formats the message and writes it as one line
+func (l *logger) log(ctx context.Context, level Level, message string, fields []interface{}) {
+	if !Enabled(level) {
+		return
+	}
+
+	if requestID := RequestID(ctx); requestID != "" {
+		fields = append([]interface{}{requestIDField, requestID}, fields...)
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	var line bytes.Buffer
+	now := time.Now().UTC().Format(timeFormat)
+	if l.format == FormatJSON {
+		writeJSON(&line, now, level, message, fields)
+	} else {
+		writeText(&line, now, level, message, fields)
+	}
+	line.WriteByte('\n')
+
+	// there is no better place to report failure to write log message
+	_, _ = l.output.Write(line.Bytes())
+}
+
+// field returns key and value of n-th field
+func field(fields []interface{}, n int) (string, interface{}) {
+	if n+1 >= len(fields) {
+		return missingKey, fields[n]
+	}
+	key, ok := fields[n].(string)
+	if !ok {
+		key = fmt.Sprint(fields[n])
+	}
+	return key, fields[n+1]
+}
+
+// fieldValue converts errors and values implementing Stringer to strings
+func fieldValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case error:
+		return v.Error()
+	case fmt.Stringer:
+		return v.String()
+	}
+	return value
+}
+
+// writeText writes message as text with fields in key=value format
+func writeText(line *bytes.Buffer, now string, level Level, message string, fields []interface{}) {
+	fmt.Fprintf(line, "%s %-5s %s", now, strings.ToUpper(level.String()), message)
+	for n := 0; n < len(fields); n += 2 {
+		key, value := field(fields, n)
+		text := fmt.Sprint(fieldValue(value))
+		if text == "" || strings.ContainsAny(text, " \t\n\"=") {
+			text = strconv.Quote(text)
+		}
+		fmt.Fprintf(line, " %s=%s", key, text)
+	}
+}
+
+// writeJSON writes message as JSON object, fields keep their order
+func writeJSON(line *bytes.Buffer, now string, level Level, message string, fields []interface{}) {
+	writeJSONField(line, "time", now, true)
+	writeJSONField(line, "level", level.String(), false)
+	writeJSONField(line, "message", message, false)
+	for n := 0; n < len(fields); n += 2 {
+		key, value := field(fields, n)
+		writeJSONField(line, key, fieldValue(value), false)
+	}
+	line.WriteByte('}')
+}
+
+// writeJSONField writes one key-value pair of JSON object. Values that can't
+// be serialized are written as strings.
+func writeJSONField(line *bytes.Buffer, key string, value interface{}, first bool) {
+	if first {
+		line.WriteByte('{')
+	} else {
+		line.WriteByte(',')
+	}
+
+	encodedKey, _ := json.Marshal(key)
+	line.Write(encodedKey)
+	line.WriteByte(':')
+
+	encodedValue, err := json.Marshal(value)
+	if err != nil {
+		encodedValue, _ = json.Marshal(fmt.Sprint(value))
+	}
+	line.Write(encodedValue)
+}
+
+// writer passes lines written by other loggers to the logger
+type writer struct {
+	level Level
+}
+
+// Write logs each written line as one message
+func (w writer) Write(p []byte) (int, error) {
+	for _, line := range strings.Split(strings.TrimRight(string(p), "\n"), "\n") {
+		std.log(context.Background(), w.level, line, nil)
+	}
+	return len(p), nil
+}
+
+// Writer returns writer that can be used as output of standard library
+// logger, for example the one used by HTTP server to report errors
+func Writer(level Level) io.Writer {
+	return writer{level: level}
+}
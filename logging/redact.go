@@ -0,0 +1,94 @@
+/*
+Copyright © 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logging
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// DefaultRedactedFields are form fields whose values are not logged unless
+// configured otherwise
+var DefaultRedactedFields = []string{
+	"configuration",
+	"csrf_token",
+	"client_secret",
+	"password",
+	"token",
+}
+
+// redactedFields contains lower case names of redacted fields
+var (
+	redactedMutex  sync.RWMutex
+	redactedFields = fieldSet(DefaultRedactedFields)
+)
+
+// fieldSet converts list of field names to set
+func fieldSet(names []string) map[string]bool {
+	set := make(map[string]bool, len(names))
+	for _, name := range names {
+		set[strings.ToLower(name)] = true
+	}
+	return set
+}
+
+// setRedactedFields replaces list of redacted fields
+func setRedactedFields(names []string) {
+	redactedMutex.Lock()
+	defer redactedMutex.Unlock()
+	redactedFields = fieldSet(names)
+}
+
+// Redacted checks whether value of form field must not be logged
+func Redacted(name string) bool {
+	redactedMutex.RLock()
+	defer redactedMutex.RUnlock()
+	return redactedFields[strings.ToLower(name)]
+}
+
+// RedactValue returns the value, or its length only for redacted fields
+func RedactValue(name string, value string) string {
+	if Redacted(name) {
+		return fmt.Sprintf("[redacted, %d bytes]", len(value))
+	}
+	return value
+}
+
+// RedactForm returns form fields suitable for logging, values of redacted
+// fields are replaced by their length
+func RedactForm(form url.Values) map[string]string {
+	fields := make(map[string]string, len(form))
+	for name, values := range form {
+		fields[name] = RedactValue(name, strings.Join(values, ","))
+	}
+	return fields
+}
+
+// RedactFields returns copy of fields, values of redacted fields are
+// replaced by their length
+func RedactFields(fields map[string]string) map[string]string {
+	if fields == nil {
+		return nil
+	}
+	redacted := make(map[string]string, len(fields))
+	for name, value := range fields {
+		redacted[name] = RedactValue(name, value)
+	}
+	return redacted
+}
@@ -0,0 +1,78 @@
+/*
+Copyright © 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+)
+
+// RequestIDHeader is HTTP header with ID of request, it is accepted from
+// clients and sent to the controller
+const RequestIDHeader = "X-Request-ID"
+
+// maximal length of request ID accepted from clients
+const maxRequestIDLength = 64
+
+// name of field with request ID in logged messages
+const requestIDField = "request_id"
+
+// requestIDKey is key of request ID stored in context
+type requestIDKey struct{}
+
+// WithRequestID returns context carrying request ID
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestID returns request ID stored in context, or empty string
+func RequestID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
+
+// NewRequestID generates random request ID
+func NewRequestID() string {
+	id := make([]byte, 16)
+	_, err := rand.Read(id)
+	if err != nil {
+		// not unique, but still usable to correlate messages
+		return hex.EncodeToString([]byte(time.Now().UTC().Format(time.RFC3339Nano)))
+	}
+	return hex.EncodeToString(id)
+}
+
+// ValidRequestID checks whether request ID sent by client can be used. Only
+// letters, digits, dashes and underscores are accepted, so the ID can't be
+// used to inject anything into logs.
+func ValidRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range requestID {
+		valid := (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || c == '-' || c == '_'
+		if !valid {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,351 @@
+/*
+Copyright © 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"github.com/tisnik/insights-operator-web-ui/auth"
+	"github.com/tisnik/insights-operator-web-ui/logging"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Endpoints used to log in and out
+const (
+	loginEndpoint  = "/login"
+	logoutEndpoint = "/logout"
+)
+
+// Cookies with session ID and with state of login in progress. The state
+// cookie binds the login to the browser that started it.
+const (
+	sessionCookieName = "insights_web_ui_session"
+	stateCookieName   = "insights_web_ui_login"
+)
+
+// query parameter with URL the user is redirected to after login
+const returnToParameter = "return"
+
+// sessionContextKey is used to store session of logged in user in request
+// context
+type sessionContextKey struct{}
+
+// bearerContextKey marks requests authenticated by bearer token instead of
+// session cookie
+type bearerContextKey struct{}
+
+// value of WWW-Authenticate header sent with 401 responses of JSON API
+const bearerChallenge = `Bearer realm="insights-operator-web-ui"`
+
+// OIDCConfiguration represents configuration of user login.
+//
+//	Enabled: users need to log in when enabled
+//	Configuration: OpenID Connect provider settings
+//	SessionLifetime: time after which users need to log in again
+type OIDCConfiguration struct {
+	Enabled bool
+	auth.Configuration
+	SessionLifetime time.Duration
+}
+
+// loginConfiguration is configuration of user login read on startup
+var loginConfiguration OIDCConfiguration
+
+// oidcProvider is used to log users in, it is nil when login is disabled
+var oidcProvider *auth.Provider
+
+// sessions contains sessions of logged in users, it is nil when login is
+// disabled
+var sessions *auth.SessionStore
+
+// loginEnabled checks whether users need to log in
+func loginEnabled() bool {
+	return sessions != nil
+}
+
+// callbackPath returns path of endpoint the provider redirects to after
+// login, it is taken from the configured redirect URL
+func callbackPath(redirectURL string) string {
+	parsed, err := url.Parse(redirectURL)
+	if err != nil || parsed.Path == "" {
+		return "/"
+	}
+	return parsed.Path
+}
+
+// secureCookies checks whether session cookie needs to be sent over HTTPS
+// only, i.e. when the UI is accessed via HTTPS
+func secureCookies() bool {
+	return strings.HasPrefix(loginConfiguration.RedirectURL, "https://")
+}
+
+// isPublicPath checks whether the endpoint can be accessed without login
+func isPublicPath(path string) bool {
+	switch path {
+	case loginEndpoint, callbackPath(loginConfiguration.RedirectURL),
+		"/bootstrap.min.css", "/bootstrap.min.js", "/ccx.css",
+		metricsEndpoint, healthzEndpoint, readyzEndpoint:
+		return true
+	}
+	return false
+}
+
+// safeReturnURL accepts local URLs only, so the login can't be used to
+// redirect users to other sites
+func safeReturnURL(returnTo string) string {
+	if !strings.HasPrefix(returnTo, "/") || strings.HasPrefix(returnTo, "//") || strings.HasPrefix(returnTo, "/\\") {
+		return "/"
+	}
+	return returnTo
+}
+
+// currentSession returns session of the logged in user, nil when login is
+// disabled
+func currentSession(request *http.Request) *auth.Session {
+	if request == nil {
+		return nil
+	}
+	session, _ := request.Context().Value(sessionContextKey{}).(*auth.Session)
+	return session
+}
+
+// currentUsername returns name of the logged in user, empty string when
+// login is disabled
+func currentUsername(request *http.Request) string {
+	session := currentSession(request)
+	if session == nil {
+		return ""
+	}
+	return session.Identity.Username
+}
+
+// formUsername returns name of user that is recorded as the author of
+// change. The logged in user is used when login is enabled, otherwise the
+// name is taken from the form.
+func formUsername(request *http.Request) string {
+	if loginEnabled() {
+		return currentUsername(request)
+	}
+	return request.FormValue(usernameParameter)
+}
+
+// sessionFromCookie finds session for the session cookie sent by browser
+func sessionFromCookie(request *http.Request) (*auth.Session, bool) {
+	cookie, err := request.Cookie(sessionCookieName)
+	if err != nil {
+		return nil, false
+	}
+	return sessions.Get(cookie.Value)
+}
+
+// bearerToken returns token sent in Authorization header, empty string when
+// the header does not contain bearer token
+func bearerToken(request *http.Request) string {
+	const prefix = "Bearer "
+	header := request.Header.Get("Authorization")
+	if len(header) <= len(prefix) || !strings.EqualFold(header[:len(prefix)], prefix) {
+		return ""
+	}
+	return strings.TrimSpace(header[len(prefix):])
+}
+
+// bearerAuthenticated checks whether the request has been authenticated by
+// bearer token, such requests are not sent by browser automatically, so
+// they don't need CSRF token
+func bearerAuthenticated(request *http.Request) bool {
+	authenticated, _ := request.Context().Value(bearerContextKey{}).(bool)
+	return authenticated
+}
+
+// loginRequiredAPIResponse rejects request sent to JSON API without valid
+// credentials, the challenge is sent in WWW-Authenticate header
+func loginRequiredAPIResponse(writer http.ResponseWriter, request *http.Request, challenge string, message string) {
+	writer.Header().Set("WWW-Authenticate", challenge)
+	errorPageResponse(writer, request, http.StatusUnauthorized, errorPageTemplate, ErrorPageDynContent{
+		Title:     "Login required",
+		Operation: request.Method + " " + request.URL.Path,
+		Message:   message,
+	})
+}
+
+// serveBearerRequest handles request sent to JSON API with bearer token. The
+// token is verified by OpenID Connect provider keys and session valid for
+// this request only is stored in request context.
+func serveBearerRequest(next http.Handler, writer http.ResponseWriter, request *http.Request, token string) {
+	identity, err := oidcProvider.VerifyBearerToken(request.Context(), token)
+	if err != nil {
+		logging.Warn(request.Context(), "Invalid bearer token", "error", err)
+		loginRequiredAPIResponse(writer, request, bearerChallenge+`, error="invalid_token"`, err.Error())
+		return
+	}
+
+	session := &auth.Session{Identity: *identity, Roles: rolesFor(*identity)}
+	ctx := context.WithValue(request.Context(), sessionContextKey{}, session)
+	ctx = context.WithValue(ctx, bearerContextKey{}, true)
+	next.ServeHTTP(writer, request.WithContext(ctx))
+}
+
+// requireLogin redirects users that are not logged in to the login page,
+// requests sent to JSON API are rejected with 401 instead. JSON API accepts
+// ID token issued by the provider in Authorization header as well. Session
+// of the logged in user is stored in request context.
+func requireLogin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if !loginEnabled() || isPublicPath(request.URL.Path) {
+			next.ServeHTTP(writer, request)
+			return
+		}
+
+		if token := bearerToken(request); token != "" && isAPIRequest(request) {
+			serveBearerRequest(next, writer, request, token)
+			return
+		}
+
+		session, found := sessionFromCookie(request)
+		if !found {
+			if isAPIRequest(request) {
+				loginRequiredAPIResponse(writer, request, bearerChallenge, "Log in or send ID token in Authorization header")
+				return
+			}
+			if request.Method != http.MethodGet {
+				http.Error(writer, "Login required", http.StatusUnauthorized)
+				return
+			}
+			loginURL := loginEndpoint + "?" + url.Values{returnToParameter: {request.URL.RequestURI()}}.Encode()
+			http.Redirect(writer, request, loginURL, http.StatusSeeOther)
+			return
+		}
+
+		ctx := context.WithValue(request.Context(), sessionContextKey{}, session)
+		next.ServeHTTP(writer, request.WithContext(ctx))
+	})
+}
+
+// newCookie constructs cookie that is not accessible to JavaScript. Negative
+// maxAge removes the cookie.
+func newCookie(name string, value string, maxAge int) *http.Cookie {
+	return &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/",
+		MaxAge:   maxAge,
+		Secure:   secureCookies(),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+// loginFailedResponse renders error page when login can't be finished
+func loginFailedResponse(writer http.ResponseWriter, request *http.Request, status int, message string) {
+	errorPageResponse(writer, request, status, errorPageTemplate, ErrorPageDynContent{
+		Title:     "Login failed",
+		Operation: "Logging in",
+		Message:   message,
+		RetryURL:  loginEndpoint,
+	})
+}
+
+// login redirects user to the login page of OpenID Connect provider
+func login(writer http.ResponseWriter, request *http.Request) {
+	returnTo := safeReturnURL(request.URL.Query().Get(returnToParameter))
+
+	state, nonce, err := sessions.StartLogin(returnTo)
+	if err != nil {
+		logging.Error(request.Context(), "Unable to start login", "error", err)
+		loginFailedResponse(writer, request, http.StatusInternalServerError, "Unable to start login")
+		return
+	}
+
+	http.SetCookie(writer, newCookie(stateCookieName, state, int(auth.LoginTimeout.Seconds())))
+	http.Redirect(writer, request, oidcProvider.AuthCodeURL(state, nonce), http.StatusFound)
+}
+
+// loginCallback finishes login when the provider redirects user back
+func loginCallback(writer http.ResponseWriter, request *http.Request) {
+	query := request.URL.Query()
+	if providerError := query.Get("error"); providerError != "" {
+		logging.Warn(request.Context(), "Login refused by provider", "error", providerError, "description", query.Get("error_description"))
+		loginFailedResponse(writer, request, http.StatusUnauthorized, "Login refused by provider: "+providerError)
+		return
+	}
+
+	state := query.Get("state")
+	stateCookie, err := request.Cookie(stateCookieName)
+	if err != nil || stateCookie.Value != state {
+		loginFailedResponse(writer, request, http.StatusBadRequest, "Login has been started in another browser")
+		return
+	}
+	http.SetCookie(writer, newCookie(stateCookieName, "", -1))
+
+	nonce, returnTo, ok := sessions.FinishLogin(state)
+	if !ok {
+		loginFailedResponse(writer, request, http.StatusBadRequest, "Login expired or has already been finished")
+		return
+	}
+
+	identity, err := oidcProvider.Exchange(request.Context(), query.Get("code"), nonce)
+	if err != nil {
+		logging.Warn(request.Context(), "Login failed", "error", err)
+		loginFailedResponse(writer, request, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	session, err := sessions.Create(*identity, rolesFor(*identity))
+	if err != nil {
+		logging.Error(request.Context(), "Unable to create session", "error", err)
+		loginFailedResponse(writer, request, http.StatusInternalServerError, "Unable to create session")
+		return
+	}
+
+	logging.Info(request.Context(), "User logged in", "username", identity.Username)
+	http.SetCookie(writer, newCookie(sessionCookieName, session.ID, int(time.Until(session.ExpiresAt).Seconds())))
+	http.Redirect(writer, request, returnTo, http.StatusSeeOther)
+}
+
+// logout removes session of the logged in user
+func logout(writer http.ResponseWriter, request *http.Request) {
+	session := currentSession(request)
+	if session != nil {
+		sessions.Delete(session.ID)
+		logging.Info(request.Context(), "User logged out", "username", session.Identity.Username)
+	}
+
+	http.SetCookie(writer, newCookie(sessionCookieName, "", -1))
+	http.Redirect(writer, request, "/", http.StatusSeeOther)
+}
+
+// initLogin contacts the OpenID Connect provider when login is enabled
+func initLogin(configuration OIDCConfiguration) error {
+	loginConfiguration = configuration
+	if !configuration.Enabled {
+		logging.Info(context.Background(), "Login is disabled")
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), auth.ProviderTimeout)
+	defer cancel()
+
+	provider, err := auth.NewProvider(ctx, configuration.Configuration)
+	if err != nil {
+		return err
+	}
+	oidcProvider = provider
+	sessions = auth.NewSessionStore(configuration.SessionLifetime)
+	return nil
+}
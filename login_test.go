@@ -0,0 +1,196 @@
+/*
+Copyright © 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/tisnik/insights-operator-web-ui/auth"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testClientID = "insights-web-ui"
+
+// newTestOIDCProvider starts provider that publishes discovery document and
+// public part of the key, it returns provider used by the web UI and its
+// issuer
+func newTestOIDCProvider(t *testing.T, key *rsa.PrivateKey) (*auth.Provider, string) {
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	mux.HandleFunc("/.well-known/openid-configuration", func(writer http.ResponseWriter, request *http.Request) {
+		writeTestJSON(t, writer, map[string]string{
+			"issuer":                 server.URL,
+			"authorization_endpoint": server.URL + "/authorize",
+			"token_endpoint":         server.URL + "/token",
+			"jwks_uri":               server.URL + "/keys",
+		})
+	})
+	mux.HandleFunc("/keys", func(writer http.ResponseWriter, request *http.Request) {
+		writeTestJSON(t, writer, map[string][]map[string]string{"keys": {{
+			"kty": "RSA",
+			"kid": "key-1",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+
+	provider, err := auth.NewProvider(context.Background(), auth.Configuration{IssuerURL: server.URL, ClientID: testClientID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return provider, server.URL
+}
+
+// writeTestJSON writes value as JSON response
+func writeTestJSON(t *testing.T, writer http.ResponseWriter, value interface{}) {
+	err := json.NewEncoder(writer).Encode(value)
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+// signTestToken constructs ID token for given audience signed by the key
+func signTestToken(t *testing.T, key *rsa.PrivateKey, issuer string, audience string) string {
+	segments := []interface{}{
+		map[string]string{"alg": "RS256", "kid": "key-1"},
+		map[string]interface{}{
+			"iss":                issuer,
+			"aud":                audience,
+			"sub":                "1234",
+			"preferred_username": "jdoe",
+			"iat":                time.Now().Unix(),
+			"exp":                time.Now().Add(time.Hour).Unix(),
+		},
+	}
+	encoded := make([]string, len(segments))
+	for i, segment := range segments {
+		value, err := json.Marshal(segment)
+		if err != nil {
+			t.Fatal(err)
+		}
+		encoded[i] = base64.RawURLEncoding.EncodeToString(value)
+	}
+
+	signed := strings.Join(encoded, ".")
+	digest := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+// TestRequireLoginBearerToken checks that JSON API accepts valid ID token in
+// Authorization header without CSRF token and that requests without valid
+// credentials are challenged
+func TestRequireLoginBearerToken(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	provider, issuer := newTestOIDCProvider(t, key)
+
+	savedProvider, savedSessions := oidcProvider, sessions
+	oidcProvider, sessions = provider, auth.NewSessionStore(time.Hour)
+	defer func() {
+		oidcProvider, sessions = savedProvider, savedSessions
+	}()
+
+	handler := requireLogin(verifyCSRF(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("X-Username", currentUsername(request))
+		writer.WriteHeader(http.StatusNoContent)
+	})))
+
+	tests := []struct {
+		name             string
+		path             string
+		authorization    string
+		expectedStatus   int
+		expectedUsername string
+		expectedError    string
+	}{
+		{
+			name:             "valid token",
+			path:             uiAPIPrefix + "/must-gather",
+			authorization:    "Bearer " + signTestToken(t, key, issuer, testClientID),
+			expectedStatus:   http.StatusNoContent,
+			expectedUsername: "jdoe",
+		},
+		{
+			name:           "token for other client",
+			path:           uiAPIPrefix + "/must-gather",
+			authorization:  "Bearer " + signTestToken(t, key, issuer, "other-client"),
+			expectedStatus: http.StatusUnauthorized,
+			expectedError:  `error="invalid_token"`,
+		},
+		{
+			name:           "invalid token",
+			path:           uiAPIPrefix + "/must-gather",
+			authorization:  "Bearer not-a-token",
+			expectedStatus: http.StatusUnauthorized,
+			expectedError:  `error="invalid_token"`,
+		},
+		{
+			name:           "no credentials",
+			path:           uiAPIPrefix + "/must-gather",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "token is not accepted by HTML pages",
+			path:           "/trigger-must-gather",
+			authorization:  "Bearer " + signTestToken(t, key, issuer, testClientID),
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader("{}"))
+			if tt.authorization != "" {
+				request.Header.Set("Authorization", tt.authorization)
+			}
+			recorder := httptest.NewRecorder()
+
+			handler.ServeHTTP(recorder, request)
+
+			if recorder.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.expectedStatus, recorder.Code, recorder.Body.String())
+			}
+			if username := recorder.Header().Get("X-Username"); username != tt.expectedUsername {
+				t.Errorf("expected user %q, got %q", tt.expectedUsername, username)
+			}
+			challenge := recorder.Header().Get("WWW-Authenticate")
+			if tt.expectedStatus == http.StatusUnauthorized && strings.HasPrefix(tt.path, uiAPIPrefix) &&
+				(!strings.HasPrefix(challenge, "Bearer ") || !strings.Contains(challenge, tt.expectedError)) {
+				t.Errorf("unexpected WWW-Authenticate header %q", challenge)
+			}
+		})
+	}
+}
@@ -0,0 +1,243 @@
+/*
+Copyright © 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package metrics implements counters, histograms and gauges exposed in
+// Prometheus text format. Each metric can have labels, values of labels are
+// passed in the same order as label names were specified.
+package metrics
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets are upper bounds of histogram buckets (in seconds) suitable
+// for HTTP request latencies
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// types of metrics written to output
+const (
+	typeCounter   = "counter"
+	typeGauge     = "gauge"
+	typeHistogram = "histogram"
+)
+
+// separator of label values in keys of series
+const labelSeparator = "\xff"
+
+// Collector is a metric that can be registered in registry
+type Collector interface {
+	// describe returns name, help text and type of the metric
+	describe() (string, string, string)
+
+	// write writes all series of the metric
+	write(output *exposition)
+}
+
+// key joins label values into key of series
+func key(labelValues []string) string {
+	return strings.Join(labelValues, labelSeparator)
+}
+
+// vec contains common parts of metrics with labels
+type vec struct {
+	name       string
+	help       string
+	labelNames []string
+}
+
+// description returns name, help text and type of the metric
+func (v *vec) description(metricType string) (string, string, string) {
+	return v.name, v.help, metricType
+}
+
+// labels pads or trims label values to the number of label names, so
+// a wrong call can't break the output
+func (v *vec) labels(labelValues []string) []string {
+	values := make([]string, len(v.labelNames))
+	copy(values, labelValues)
+	return values
+}
+
+// CounterVec is a counter with labels
+type CounterVec struct {
+	vec
+	mutex  sync.Mutex
+	values map[string]*counterValue
+}
+
+// counterValue is value of one counter series
+type counterValue struct {
+	labels []string
+	value  float64
+}
+
+// NewCounterVec constructs counter with given labels
+func NewCounterVec(name string, help string, labelNames ...string) *CounterVec {
+	return &CounterVec{
+		vec:    vec{name: name, help: help, labelNames: labelNames},
+		values: map[string]*counterValue{},
+	}
+}
+
+// Inc increments counter with given label values by one
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add increments counter with given label values, negative values are ignored
+func (c *CounterVec) Add(value float64, labelValues ...string) {
+	if value < 0 {
+		return
+	}
+	values := c.labels(labelValues)
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	counter, found := c.values[key(values)]
+	if !found {
+		counter = &counterValue{labels: values}
+		c.values[key(values)] = counter
+	}
+	counter.value += value
+}
+
+func (c *CounterVec) describe() (string, string, string) {
+	return c.description(typeCounter)
+}
+
+func (c *CounterVec) write(output *exposition) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	keys := make([]string, 0, len(c.values))
+	for k := range c.values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		counter := c.values[k]
+		output.sample(c.name, c.labelNames, counter.labels, counter.value)
+	}
+}
+
+// HistogramVec is a histogram with labels
+type HistogramVec struct {
+	vec
+	buckets []float64
+	mutex   sync.Mutex
+	values  map[string]*histogramValue
+}
+
+// histogramValue is value of one histogram series, counts are cumulative
+type histogramValue struct {
+	labels []string
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// NewHistogramVec constructs histogram with given bucket upper bounds and
+// labels. DefaultBuckets are used when no buckets are specified.
+func NewHistogramVec(name string, help string, buckets []float64, labelNames ...string) *HistogramVec {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	sorted := append([]float64(nil), buckets...)
+	sort.Float64s(sorted)
+	return &HistogramVec{
+		vec:     vec{name: name, help: help, labelNames: labelNames},
+		buckets: sorted,
+		values:  map[string]*histogramValue{},
+	}
+}
+
+// Observe adds observed value to histogram with given label values
+func (h *HistogramVec) Observe(value float64, labelValues ...string) {
+	values := h.labels(labelValues)
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	histogram, found := h.values[key(values)]
+	if !found {
+		histogram = &histogramValue{labels: values, counts: make([]uint64, len(h.buckets))}
+		h.values[key(values)] = histogram
+	}
+	for i, upperBound := range h.buckets {
+		if value <= upperBound {
+			histogram.counts[i]++
+		}
+	}
+	histogram.sum += value
+	histogram.count++
+}
+
+func (h *HistogramVec) describe() (string, string, string) {
+	return h.description(typeHistogram)
+}
+
+func (h *HistogramVec) write(output *exposition) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	keys := make([]string, 0, len(h.values))
+	for k := range h.values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		histogram := h.values[k]
+		for i, upperBound := range h.buckets {
+			output.bucket(h.name, h.labelNames, histogram.labels, formatValue(upperBound), histogram.counts[i])
+		}
+		output.bucket(h.name, h.labelNames, histogram.labels, "+Inf", histogram.count)
+		output.sample(h.name+"_sum", h.labelNames, histogram.labels, histogram.sum)
+		output.sample(h.name+"_count", h.labelNames, histogram.labels, float64(histogram.count))
+	}
+}
+
+// LabeledValue is value of one gauge series returned by GaugeFunc
+type LabeledValue struct {
+	LabelValues []string
+	Value       float64
+}
+
+// GaugeFunc is a gauge whose values are read by callback when metrics are
+// collected, for example from state of circuit breaker
+type GaugeFunc struct {
+	vec
+	collect func() []LabeledValue
+}
+
+// NewGaugeFunc constructs gauge with given labels, the callback returns
+// values of all series
+func NewGaugeFunc(name string, help string, labelNames []string, collect func() []LabeledValue) *GaugeFunc {
+	return &GaugeFunc{
+		vec:     vec{name: name, help: help, labelNames: labelNames},
+		collect: collect,
+	}
+}
+
+func (g *GaugeFunc) describe() (string, string, string) {
+	return g.description(typeGauge)
+}
+
+func (g *GaugeFunc) write(output *exposition) {
+	for _, value := range g.collect() {
+		output.sample(g.name, g.labelNames, g.labels(value.LabelValues), value.Value)
+	}
+}
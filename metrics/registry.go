@@ -0,0 +1,163 @@
+/*
+Copyright © 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ContentType is content type of Prometheus text format
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Registry contains all metrics exposed by the service
+type Registry struct {
+	mutex      sync.Mutex
+	collectors map[string]Collector
+}
+
+// NewRegistry constructs empty registry
+func NewRegistry() *Registry {
+	return &Registry{collectors: map[string]Collector{}}
+}
+
+// Register adds metrics to registry, names of metrics need to be unique
+func (r *Registry) Register(collectors ...Collector) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for _, collector := range collectors {
+		name, _, _ := collector.describe()
+		if _, found := r.collectors[name]; found {
+			return fmt.Errorf("metric %s is already registered", name)
+		}
+		r.collectors[name] = collector
+	}
+	return nil
+}
+
+// MustRegister adds metrics to registry and panics when any name is
+// registered already
+func (r *Registry) MustRegister(collectors ...Collector) {
+	err := r.Register(collectors...)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// write writes all metrics sorted by name
+func (r *Registry) write(output *exposition) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	names := make([]string, 0, len(r.collectors))
+	for name := range r.collectors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		collector := r.collectors[name]
+		_, help, metricType := collector.describe()
+		fmt.Fprintf(&output.buffer, "# HELP %s %s\n", name, escapeHelp(help))
+		fmt.Fprintf(&output.buffer, "# TYPE %s %s\n", name, metricType)
+		collector.write(output)
+	}
+}
+
+// ServeHTTP writes all metrics in Prometheus text format
+func (r *Registry) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	var output exposition
+	r.write(&output)
+
+	writer.Header().Set("Content-Type", ContentType)
+	// client disconnected when it fails, there is nobody to report it to
+	_, _ = output.buffer.WriteTo(writer)
+}
+
+// exposition collects output in Prometheus text format
+type exposition struct {
+	buffer bytes.Buffer
+}
+
+// sample writes one sample with labels
+func (e *exposition) sample(name string, labelNames []string, labelValues []string, value float64) {
+	e.buffer.WriteString(name)
+	e.writeLabels(labelNames, labelValues, "")
+	e.buffer.WriteByte(' ')
+	e.buffer.WriteString(formatValue(value))
+	e.buffer.WriteByte('\n')
+}
+
+// bucket writes one histogram bucket, upper bound is written as le label
+func (e *exposition) bucket(name string, labelNames []string, labelValues []string, upperBound string, count uint64) {
+	e.buffer.WriteString(name)
+	e.buffer.WriteString("_bucket")
+	e.writeLabels(labelNames, labelValues, upperBound)
+	e.buffer.WriteByte(' ')
+	e.buffer.WriteString(strconv.FormatUint(count, 10))
+	e.buffer.WriteByte('\n')
+}
+
+// writeLabels writes labels in {name="value",...} format, nothing is written
+// for metrics without labels
+func (e *exposition) writeLabels(labelNames []string, labelValues []string, upperBound string) {
+	if len(labelNames) == 0 && upperBound == "" {
+		return
+	}
+
+	labels := make([]string, 0, len(labelNames)+1)
+	for i, name := range labelNames {
+		labels = append(labels, name+`="`+escapeLabelValue(labelValues[i])+`"`)
+	}
+	if upperBound != "" {
+		labels = append(labels, `le="`+upperBound+`"`)
+	}
+	e.buffer.WriteByte('{')
+	e.buffer.WriteString(strings.Join(labels, ","))
+	e.buffer.WriteByte('}')
+}
+
+// formatValue formats sample value, infinities and NaN are written the way
+// Prometheus expects them
+func formatValue(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+// escapeHelp escapes backslashes and new lines in help text
+func escapeHelp(help string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(help)
+}
+
+// escapeLabelValue escapes backslashes, quotes and new lines in label value
+func escapeLabelValue(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
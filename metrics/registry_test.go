@@ -0,0 +1,116 @@
+/*
+Copyright © 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// expected output of the registry filled by newTestRegistry
+const expectedExposition = `# HELP test_build_info Build information, value is always 1
+# TYPE test_build_info gauge
+test_build_info{version="1.0 \"final\""} 1
+test_build_info{version="C:\\path\nnext line"} NaN
+# HELP test_latency_seconds Latency of requests
+# TYPE test_latency_seconds histogram
+test_latency_seconds_bucket{endpoint="/a",le="0.5"} 1
+test_latency_seconds_bucket{endpoint="/a",le="1"} 2
+test_latency_seconds_bucket{endpoint="/a",le="+Inf"} 3
+test_latency_seconds_sum{endpoint="/a"} 3
+test_latency_seconds_count{endpoint="/a"} 3
+test_latency_seconds_bucket{endpoint="/b",le="0.5"} 0
+test_latency_seconds_bucket{endpoint="/b",le="1"} 0
+test_latency_seconds_bucket{endpoint="/b",le="+Inf"} 1
+test_latency_seconds_sum{endpoint="/b"} +Inf
+test_latency_seconds_count{endpoint="/b"} 1
+# HELP test_requests_total Number of requests with \\ backslash,\nhelp on two lines
+# TYPE test_requests_total counter
+test_requests_total{method="GET",path=""} 1
+test_requests_total{method="POST",path="/a\"b\\c\nd"} 2.5
+# HELP test_total Counter without labels
+# TYPE test_total counter
+test_total 1
+# HELP test_unlabeled_seconds Histogram without labels
+# TYPE test_unlabeled_seconds histogram
+test_unlabeled_seconds_bucket{le="0.1"} 1
+test_unlabeled_seconds_bucket{le="+Inf"} 1
+test_unlabeled_seconds_sum 0.1
+test_unlabeled_seconds_count 1
+`
+
+// newTestRegistry constructs registry with metrics of all types, label
+// values that need to be escaped and histogram buckets specified out of
+// order
+func newTestRegistry() *Registry {
+	requests := NewCounterVec("test_requests_total", "Number of requests with \\ backslash,\nhelp on two lines", "method", "path")
+	requests.Add(2.5, "POST", "/a\"b\\c\nd")
+	requests.Inc("GET")
+	requests.Add(-1, "GET")
+
+	total := NewCounterVec("test_total", "Counter without labels")
+	total.Inc()
+
+	latency := NewHistogramVec("test_latency_seconds", "Latency of requests", []float64{1, 0.5}, "endpoint")
+	latency.Observe(0.3, "/a")
+	latency.Observe(1, "/a")
+	latency.Observe(1.7, "/a")
+	latency.Observe(math.Inf(1), "/b")
+
+	unlabeled := NewHistogramVec("test_unlabeled_seconds", "Histogram without labels", []float64{0.1})
+	unlabeled.Observe(0.1)
+
+	info := NewGaugeFunc("test_build_info", "Build information, value is always 1", []string{"version"}, func() []LabeledValue {
+		return []LabeledValue{
+			{LabelValues: []string{`1.0 "final"`}, Value: 1},
+			{LabelValues: []string{"C:\\path\nnext line", "ignored"}, Value: math.NaN()},
+		}
+	})
+
+	registry := NewRegistry()
+	registry.MustRegister(requests, total, latency, unlabeled, info)
+	return registry
+}
+
+// TestExposition compares output of registry with expected Prometheus text
+// format
+func TestExposition(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	newTestRegistry().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if contentType := recorder.Header().Get("Content-Type"); contentType != ContentType {
+		t.Errorf("unexpected content type %q", contentType)
+	}
+	if output := recorder.Body.String(); output != expectedExposition {
+		t.Errorf("unexpected output:\n%s\nexpected:\n%s", output, expectedExposition)
+	}
+}
+
+// TestRegisterDuplicate checks that metric names need to be unique
+func TestRegisterDuplicate(t *testing.T) {
+	registry := NewRegistry()
+	err := registry.Register(NewCounterVec("test_total", "First"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = registry.Register(NewCounterVec("test_total", "Second"))
+	if err == nil {
+		t.Error("metric registered twice")
+	}
+}
@@ -0,0 +1,262 @@
+/*
+Copyright © 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Mock OpenID Connect provider that can be used to try the web UI login
+// locally. Any user name entered into the login form is accepted. Never use
+// it in production.
+//
+// Usage:
+//
+//	go run ./mock-oidc -address :9090 -client-id insights-operator-web-ui -client-secret secret
+package main
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"flag"
+	"html/template"
+	"log"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ID of the only signing key
+const keyID = "mock-key"
+
+// login form displayed by the authorization endpoint, it is posted to the
+// same URL, so all query parameters are preserved
+var loginForm = template.Must(template.New("login").Parse(`<!DOCTYPE html>
+<html><head><title>Mock OpenID Connect provider</title></head>
+<body>
+<h1>Mock OpenID Connect provider</h1>
+<form method="POST">
+<p>User name: <input type="text" name="login" autofocus/></p>
+<p>Groups (comma separated): <input type="text" name="login_groups"/></p>
+<p><button type="submit">Log in</button></p>
+</form>
+</body></html>
+`))
+
+// issuedCode represents authorization code that can be exchanged for token
+type issuedCode struct {
+	clientID    string
+	redirectURI string
+	nonce       string
+	username    string
+	groups      []string
+}
+
+// provider is the mock OpenID Connect provider
+type provider struct {
+	issuer       string
+	clientID     string
+	clientSecret string
+	key          *rsa.PrivateKey
+
+	mutex sync.Mutex
+	codes map[string]issuedCode
+}
+
+func encodeSegment(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func randomString() string {
+	buffer := make([]byte, 16)
+	_, err := rand.Read(buffer)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return encodeSegment(buffer)
+}
+
+func writeJSON(writer http.ResponseWriter, data interface{}) {
+	writer.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(writer).Encode(data)
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+func (p *provider) discovery(writer http.ResponseWriter, request *http.Request) {
+	writeJSON(writer, map[string]interface{}{
+		"issuer":                                p.issuer,
+		"authorization_endpoint":                p.issuer + "/authorize",
+		"token_endpoint":                        p.issuer + "/token",
+		"jwks_uri":                              p.issuer + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+	})
+}
+
+func (p *provider) jwks(writer http.ResponseWriter, request *http.Request) {
+	writeJSON(writer, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"use": "sig",
+			"alg": "RS256",
+			"kid": keyID,
+			"n":   encodeSegment(p.key.N.Bytes()),
+			"e":   encodeSegment(big.NewInt(int64(p.key.E)).Bytes()),
+		}},
+	})
+}
+
+// authorize displays login form and redirects back to the client with
+// authorization code when the form is submitted
+func (p *provider) authorize(writer http.ResponseWriter, request *http.Request) {
+	err := request.ParseForm()
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if request.Form.Get("client_id") != p.clientID {
+		http.Error(writer, "unknown client", http.StatusBadRequest)
+		return
+	}
+
+	username := request.PostForm.Get("login")
+	if request.Method != http.MethodPost || username == "" {
+		err = loginForm.Execute(writer, nil)
+		if err != nil {
+			log.Println(err)
+		}
+		return
+	}
+
+	var groups []string
+	for _, group := range strings.Split(request.PostForm.Get("login_groups"), ",") {
+		if group = strings.TrimSpace(group); group != "" {
+			groups = append(groups, group)
+		}
+	}
+
+	code := randomString()
+	p.mutex.Lock()
+	p.codes[code] = issuedCode{
+		clientID:    p.clientID,
+		redirectURI: request.Form.Get("redirect_uri"),
+		nonce:       request.Form.Get("nonce"),
+		username:    username,
+		groups:      groups,
+	}
+	p.mutex.Unlock()
+
+	query := url.Values{"code": {code}, "state": {request.Form.Get("state")}}
+	http.Redirect(writer, request, request.Form.Get("redirect_uri")+"?"+query.Encode(), http.StatusFound)
+}
+
+// token exchanges authorization code for ID token
+func (p *provider) token(writer http.ResponseWriter, request *http.Request) {
+	clientID, clientSecret, ok := request.BasicAuth()
+	if !ok || clientID != p.clientID || clientSecret != p.clientSecret {
+		http.Error(writer, `{"error":"invalid_client"}`, http.StatusUnauthorized)
+		return
+	}
+
+	p.mutex.Lock()
+	code, found := p.codes[request.FormValue("code")]
+	delete(p.codes, request.FormValue("code"))
+	p.mutex.Unlock()
+
+	if !found || code.redirectURI != request.FormValue("redirect_uri") {
+		http.Error(writer, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+		return
+	}
+
+	idToken, err := p.signIDToken(code)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(writer, map[string]interface{}{
+		"access_token": randomString(),
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     idToken,
+	})
+}
+
+// signIDToken constructs ID token signed by RS256
+func (p *provider) signIDToken(code issuedCode) (string, error) {
+	now := time.Now()
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": keyID})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]interface{}{
+		"iss":                p.issuer,
+		"sub":                "mock-" + code.username,
+		"aud":                code.clientID,
+		"iat":                now.Unix(),
+		"exp":                now.Add(time.Hour).Unix(),
+		"nonce":              code.nonce,
+		"preferred_username": code.username,
+		"email":              code.username + "@example.com",
+		"groups":             code.groups,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	signed := encodeSegment(header) + "." + encodeSegment(claims)
+	digest := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, p.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return signed + "." + encodeSegment(signature), nil
+}
+
+func main() {
+	address := flag.String("address", ":9090", "address to listen on")
+	issuer := flag.String("issuer", "http://localhost:9090", "issuer URL")
+	clientID := flag.String("client-id", "insights-operator-web-ui", "client ID")
+	clientSecret := flag.String("client-secret", "secret", "client secret")
+	flag.Parse()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	p := &provider{
+		issuer:       *issuer,
+		clientID:     *clientID,
+		clientSecret: *clientSecret,
+		key:          key,
+		codes:        map[string]issuedCode{},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", p.discovery)
+	mux.HandleFunc("/jwks", p.jwks)
+	mux.HandleFunc("/authorize", p.authorize)
+	mux.HandleFunc("/token", p.token)
+
+	log.Println("Mock OpenID Connect provider listening on", *address)
+	server := &http.Server{Addr: *address, Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+	log.Fatal(server.ListenAndServe())
+}
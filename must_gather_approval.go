@@ -0,0 +1,360 @@
+/*
+Copyright © 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/tisnik/insights-operator-web-ui/approval"
+	"github.com/tisnik/insights-operator-web-ui/audit"
+	"github.com/tisnik/insights-operator-web-ui/client"
+	"github.com/tisnik/insights-operator-web-ui/logging"
+	"github.com/tisnik/insights-operator-web-ui/router"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// actions recorded in audit log for must-gather requests, approval itself
+// is recorded as trigger_must_gather operation
+const (
+	actionRequestMustGather = "request_must_gather"
+	actionRejectMustGather  = "reject_must_gather"
+	actionCancelMustGather  = "cancel_must_gather"
+)
+
+// form parameter with reason for rejection or cancellation
+const commentParameter = "comment"
+
+// MustGatherConfiguration represents configuration of must-gather approval
+// and validation of must-gather form.
+//
+//	RequireApproval: must-gather is sent to the controller only after it has
+//	                 been approved by another user
+//	RequestsFile: JSON file with requests, they are kept in memory only when
+//	              not specified
+//	AllowedLinkHosts: hosts the link to customer ACK can point to, any host
+//	                  is allowed when empty
+//	MinReasonLength: minimal number of characters in reason
+type MustGatherConfiguration struct {
+	RequireApproval  bool
+	RequestsFile     string
+	AllowedLinkHosts []string
+	MinReasonLength  int
+}
+
+// mustGatherConfiguration contains rules for must-gather form validation
+var mustGatherConfiguration MustGatherConfiguration
+
+// mustGatherRequests keeps requests waiting for approval, it is nil when
+// approval is not required
+var mustGatherRequests *approval.Store
+
+// initMustGather remembers rules for must-gather form validation and opens
+// store of must-gather requests when approval is required. Approver needs to
+// be a different user than requester, so login needs to be enabled.
+func initMustGather(configuration MustGatherConfiguration) error {
+	mustGatherConfiguration = configuration
+	if !configuration.RequireApproval {
+		logging.Info(context.Background(), "Must-gather is triggered without approval")
+		return nil
+	}
+	if !loginEnabled() {
+		return errors.New("Must-gather approval requires user login to be enabled")
+	}
+
+	store, err := approval.NewStore(configuration.RequestsFile)
+	if err != nil {
+		return err
+	}
+	mustGatherRequests = store
+	logging.Info(context.Background(), "Must-gather needs to be approved by another user")
+	return nil
+}
+
+// mustGatherRequestURL returns address of page with the request
+func mustGatherRequestURL(id int) string {
+	return fmt.Sprintf("%s/%d", mustGatherRequestsEndpoint, id)
+}
+
+// MustGatherRequestsDynContent represents dynamic part of HTML page with
+// list of must-gather requests.
+//
+//	Enabled: whether must-gather approval is required
+//	Requests: all requests, the newest first
+type MustGatherRequestsDynContent struct {
+	Enabled  bool               `json:"enabled"`
+	Requests []approval.Request `json:"requests"`
+}
+
+// MustGatherRequestDynContent represents dynamic part of HTML page with
+// one must-gather request.
+//
+//	Request: the request itself
+//	CanDecide: whether the current user can approve or reject the request
+//	CanCancel: whether the current user can cancel the request
+type MustGatherRequestDynContent struct {
+	Request   approval.Request `json:"request"`
+	CanDecide bool             `json:"can_decide"`
+	CanCancel bool             `json:"can_cancel"`
+}
+
+// approvalErrorResponse displays error page when request can't be found,
+// approved or rejected
+func approvalErrorResponse(writer http.ResponseWriter, request *http.Request, operation string, retryURL string, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, approval.ErrNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, approval.ErrNotPending):
+		status = http.StatusConflict
+	case errors.Is(err, approval.ErrSelfApproval), errors.Is(err, approval.ErrNotRequester), errors.Is(err, approval.ErrMissingDecider):
+		status = http.StatusForbidden
+	}
+
+	logging.Warn(request.Context(), "Must-gather request can't be processed", "operation", operation, "error", err)
+	errorPageResponse(writer, request, status, errorPageTemplate, ErrorPageDynContent{
+		Title:     "Must-gather request can't be processed",
+		Operation: operation,
+		Message:   err.Error(),
+		RetryURL:  retryURL,
+	})
+}
+
+// requestID reads ID of must-gather request from path
+func requestID(writer http.ResponseWriter, request *http.Request) (int, bool) {
+	id, err := strconv.Atoi(router.Param(request, idParameter))
+	if err != nil {
+		approvalErrorResponse(writer, request, "Reading must-gather request", mustGatherRequestsEndpoint, approval.ErrNotFound)
+		return 0, false
+	}
+	return id, true
+}
+
+// submitMustGather stores must-gather request, it waits for approval then
+func submitMustGather(writer http.ResponseWriter, request *http.Request, mustGather approval.Request) {
+	stored, err := mustGatherRequests.Submit(mustGather)
+	recordAuditEvent(request, audit.Event{
+		Action: actionRequestMustGather,
+		Target: mustGather.ClusterName,
+		Parameters: map[string]string{
+			reasonParameter: mustGather.Reason,
+			linkParameter:   mustGather.Link,
+		},
+	}, err)
+	if err != nil {
+		approvalErrorResponse(writer, request, "Requesting must-gather", "/list-clusters", err)
+		return
+	}
+
+	logging.Info(request.Context(), "Must-gather request waits for approval", "id", stored.ID, "cluster", stored.ClusterName, "requested_by", stored.RequestedBy)
+	if isAPIRequest(request) {
+		writeJSON(writer, request, http.StatusAccepted, stored)
+		return
+	}
+	http.Redirect(writer, request, mustGatherRequestURL(stored.ID), http.StatusSeeOther)
+}
+
+// mustGatherRequestsPage displays all must-gather requests
+func mustGatherRequestsPage(writer http.ResponseWriter, request *http.Request) {
+	dynData := MustGatherRequestsDynContent{
+		Enabled: mustGatherRequests != nil,
+	}
+	if mustGatherRequests != nil {
+		dynData.Requests = mustGatherRequests.List()
+	}
+	renderPage(writer, request, http.StatusOK, "must_gather_requests.html", dynData)
+}
+
+// mustGatherRequestPage displays one must-gather request together with
+// approve and reject forms
+func mustGatherRequestPage(writer http.ResponseWriter, request *http.Request) {
+	if mustGatherRequests == nil {
+		notFound(writer, request)
+		return
+	}
+	id, ok := requestID(writer, request)
+	if !ok {
+		return
+	}
+
+	mustGather, err := mustGatherRequests.Get(id)
+	if err != nil {
+		approvalErrorResponse(writer, request, "Reading must-gather request", mustGatherRequestsEndpoint, err)
+		return
+	}
+
+	waiting := mustGather.Waiting()
+	requester := currentUsername(request) == mustGather.RequestedBy
+	dynData := MustGatherRequestDynContent{
+		Request:   mustGather,
+		CanDecide: waiting && !requester,
+		CanCancel: waiting && requester,
+	}
+	renderPage(writer, request, http.StatusOK, "must_gather_request.html", dynData)
+}
+
+// detachedContext keeps values of parent context (request ID, response
+// status), but it is never cancelled and has no deadline
+type detachedContext struct {
+	parent context.Context
+}
+
+func (c detachedContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (c detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+func (c detachedContext) Err() error {
+	return nil
+}
+
+func (c detachedContext) Value(key interface{}) interface{} {
+	return c.parent.Value(key)
+}
+
+// withoutCancel returns context that is not cancelled when the client
+// disconnects
+func withoutCancel(ctx context.Context) context.Context {
+	return detachedContext{parent: ctx}
+}
+
+// approvalDelivery decides whether approved request has reached the
+// controller. Must-gather trigger is not idempotent, so the request waits
+// for approval again only when it surely has not been sent.
+func approvalDelivery(err error) approval.Delivery {
+	switch {
+	case err == nil:
+		return approval.Delivered
+	case client.IsNotSent(err):
+		return approval.NotDelivered
+	}
+	return approval.DeliveryUnknown
+}
+
+// approveMustGather sends approved must-gather request to the controller
+func approveMustGather(writer http.ResponseWriter, request *http.Request) {
+	if mustGatherRequests == nil {
+		notFound(writer, request)
+		return
+	}
+	id, ok := requestID(writer, request)
+	if !ok {
+		return
+	}
+	approver := formUsername(request)
+
+	mustGather, err := mustGatherRequests.StartApproval(id, approver)
+	if err != nil {
+		approvalErrorResponse(writer, request, "Approving must-gather request", mustGatherRequestURL(id), err)
+		return
+	}
+
+	parameters := map[string]string{
+		idParameter:          strconv.Itoa(mustGather.ID),
+		reasonParameter:      mustGather.Reason,
+		linkParameter:        mustGather.Link,
+		requestedByParameter: mustGather.RequestedBy,
+	}
+	// approval is not interrupted when the approver closes the page, the
+	// call is still limited by the operation timeout
+	detached := request.WithContext(withoutCancel(request.Context()))
+	err = auditAction(detached, client.OperationTriggerMustGather, mustGather.ClusterName, parameters, func(ctx context.Context) error {
+		return controller.TriggerMustGather(ctx, mustGather.ClusterName, mustGather.RequestedBy, mustGather.Reason, mustGather.Link)
+	})
+
+	finishErr := mustGatherRequests.FinishApproval(id, approver, approvalDelivery(err))
+	if finishErr != nil {
+		logging.Error(request.Context(), "Unable to record approval of must-gather request", "id", id, "error", finishErr)
+	}
+
+	if err != nil {
+		controllerErrorResponse(writer, request, client.OperationTriggerMustGather, err)
+		return
+	}
+
+	logging.Info(request.Context(), "Must-gather request has been approved", "id", id, "approver", approver)
+	actionDone(writer, request, http.StatusOK, mustGatherRequestURL(id), "Must-gather request has been approved")
+}
+
+// rejectMustGather rejects must-gather request, it is never sent to the
+// controller then
+func rejectMustGather(writer http.ResponseWriter, request *http.Request) {
+	if mustGatherRequests == nil {
+		notFound(writer, request)
+		return
+	}
+	id, ok := requestID(writer, request)
+	if !ok {
+		return
+	}
+	rejecter := formUsername(request)
+	comment := request.FormValue(commentParameter)
+
+	mustGather, err := mustGatherRequests.Reject(id, rejecter, comment)
+	if err != nil {
+		approvalErrorResponse(writer, request, "Rejecting must-gather request", mustGatherRequestURL(id), err)
+		return
+	}
+	recordAuditEvent(request, audit.Event{
+		Action: actionRejectMustGather,
+		Target: mustGather.ClusterName,
+		Parameters: map[string]string{
+			idParameter:      strconv.Itoa(id),
+			commentParameter: comment,
+		},
+	}, nil)
+
+	logging.Info(request.Context(), "Must-gather request has been rejected", "id", id, "rejecter", rejecter)
+	actionDone(writer, request, http.StatusOK, mustGatherRequestURL(id), "Must-gather request has been rejected")
+}
+
+// cancelMustGather cancels must-gather request on behalf of user that
+// submitted it, it is never sent to the controller then
+func cancelMustGather(writer http.ResponseWriter, request *http.Request) {
+	if mustGatherRequests == nil {
+		notFound(writer, request)
+		return
+	}
+	id, ok := requestID(writer, request)
+	if !ok {
+		return
+	}
+	requester := formUsername(request)
+	comment := request.FormValue(commentParameter)
+
+	mustGather, err := mustGatherRequests.Cancel(id, requester, comment)
+	if err != nil {
+		approvalErrorResponse(writer, request, "Cancelling must-gather request", mustGatherRequestURL(id), err)
+		return
+	}
+	recordAuditEvent(request, audit.Event{
+		Action: actionCancelMustGather,
+		Target: mustGather.ClusterName,
+		Parameters: map[string]string{
+			idParameter:      strconv.Itoa(id),
+			commentParameter: comment,
+		},
+	}, nil)
+
+	logging.Info(request.Context(), "Must-gather request has been cancelled", "id", id, "requester", requester)
+	actionDone(writer, request, http.StatusOK, mustGatherRequestURL(id), "Must-gather request has been cancelled")
+}
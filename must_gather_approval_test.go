@@ -0,0 +1,133 @@
+/*
+Copyright © 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/tisnik/insights-operator-web-ui/approval"
+	"github.com/tisnik/insights-operator-web-ui/client"
+	"github.com/tisnik/insights-operator-web-ui/router"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// mustGatherController is fake controller that triggers must-gather by
+// given function, other operations are not implemented
+type mustGatherController struct {
+	client.ControllerClient
+	trigger func(ctx context.Context) error
+}
+
+func (c *mustGatherController) TriggerMustGather(ctx context.Context, clusterName, username, reason, link string) error {
+	return c.trigger(ctx)
+}
+
+// TestApproveMustGather checks state of the request after it has been sent
+// to the controller. The request waits for approval again only when it
+// surely has not reached the controller.
+func TestApproveMustGather(t *testing.T) {
+	refused := &url.Error{Op: "Post", URL: "http://controller", Err: &net.OpError{Op: "dial", Net: "tcp", Err: fmt.Errorf("connection refused")}}
+
+	tests := []struct {
+		name           string
+		trigger        func(ctx context.Context) error
+		clientGone     bool
+		expectedStatus approval.Status
+	}{
+		{
+			name:           "must-gather triggered",
+			trigger:        func(ctx context.Context) error { return nil },
+			expectedStatus: approval.StatusApproved,
+		},
+		{
+			name:           "connection refused",
+			trigger:        func(ctx context.Context) error { return refused },
+			expectedStatus: approval.StatusPending,
+		},
+		{
+			name:           "circuit breaker open",
+			trigger:        func(ctx context.Context) error { return client.ErrControllerUnavailable },
+			expectedStatus: approval.StatusPending,
+		},
+		{
+			name: "timeout",
+			trigger: func(ctx context.Context) error {
+				return fmt.Errorf("Communication error with the server %w", context.DeadlineExceeded)
+			},
+			expectedStatus: approval.StatusInterrupted,
+		},
+		{
+			name:           "server error",
+			trigger:        func(ctx context.Context) error { return &client.StatusError{StatusCode: http.StatusBadGateway} },
+			expectedStatus: approval.StatusInterrupted,
+		},
+		{
+			name: "approver closed the page",
+			trigger: func(ctx context.Context) error {
+				return ctx.Err()
+			},
+			clientGone:     true,
+			expectedStatus: approval.StatusApproved,
+		},
+	}
+
+	originalController, originalRequests := controller, mustGatherRequests
+	defer func() {
+		controller, mustGatherRequests = originalController, originalRequests
+	}()
+
+	r := router.New()
+	r.HandleFunc(uiAPIPrefix+"/must-gather-requests/{id}/approve", approveMustGather, http.MethodPost)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, err := approval.NewStore("")
+			if err != nil {
+				t.Fatal(err)
+			}
+			mustGatherRequests = store
+			controller = &mustGatherController{trigger: tt.trigger}
+			submitted, err := store.Submit(approval.Request{ClusterName: "cluster-1", Reason: "crash loop", RequestedBy: "jdoe"})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tt.clientGone {
+				cancel()
+			}
+			path := fmt.Sprintf("%s/must-gather-requests/%d/approve", uiAPIPrefix, submitted.ID)
+			request := httptest.NewRequest(http.MethodPost, path, strings.NewReader("username=asmith")).WithContext(ctx)
+			request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			r.ServeHTTP(httptest.NewRecorder(), request)
+
+			approved, err := store.Get(submitted.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if approved.Status != tt.expectedStatus {
+				t.Errorf("expected status %s, got %s", tt.expectedStatus, approved.Status)
+			}
+		})
+	}
+}
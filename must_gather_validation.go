@@ -0,0 +1,130 @@
+/*
+Copyright © 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// form parameters with cluster must-gather is requested for
+const (
+	clusterIDParameter   = "clusterid"
+	clusterNameParameter = "clustername"
+)
+
+// MustGatherForm represents must-gather form filled in by user. It is
+// displayed again with errors when any field is not valid.
+//
+//	ClusterID: ID of cluster
+//	ClusterName: name of cluster
+//	Username: user requesting must-gather, empty when user is logged in
+//	Reason: why the data need to be gathered
+//	Link: link to document with customer ACK
+//	Errors: error messages for fields with invalid values
+type MustGatherForm struct {
+	ClusterID   string            `json:"clusterid"`
+	ClusterName string            `json:"clustername"`
+	Username    string            `json:"username,omitempty"`
+	Reason      string            `json:"reason"`
+	Link        string            `json:"link"`
+	Errors      map[string]string `json:"errors,omitempty"`
+}
+
+// setError records error for form field, the first error is kept only
+func (f *MustGatherForm) setError(field string, message string) {
+	if f.Errors == nil {
+		f.Errors = map[string]string{}
+	}
+	if _, found := f.Errors[field]; !found {
+		f.Errors[field] = message
+	}
+}
+
+// Valid checks whether all fields have valid values
+func (f *MustGatherForm) Valid() bool {
+	return len(f.Errors) == 0
+}
+
+// validate checks all fields of the form against configured rules
+func (f *MustGatherForm) validate(configuration MustGatherConfiguration) {
+	f.validateRequired()
+	if f.ClusterID != "" {
+		if _, err := strconv.Atoi(f.ClusterID); err != nil {
+			f.setError(clusterIDParameter, "Cluster ID must be a number")
+		}
+	}
+	f.validateReason(configuration.MinReasonLength)
+	f.validateLink(configuration.AllowedLinkHosts)
+}
+
+// validateRequired checks that all required fields are filled in. User name
+// is required only when user is not logged in.
+func (f *MustGatherForm) validateRequired() {
+	required := map[string]string{
+		clusterIDParameter:   f.ClusterID,
+		clusterNameParameter: f.ClusterName,
+		reasonParameter:      f.Reason,
+		linkParameter:        f.Link,
+	}
+	if !loginEnabled() {
+		required[usernameParameter] = f.Username
+	}
+	for field, value := range required {
+		if value == "" {
+			f.setError(field, "This field is required")
+		}
+	}
+}
+
+// validateReason checks that the reason is long enough
+func (f *MustGatherForm) validateReason(minLength int) {
+	if f.Reason != "" && utf8.RuneCountInString(f.Reason) < minLength {
+		f.setError(reasonParameter, fmt.Sprintf("Reason must be at least %d characters long", minLength))
+	}
+}
+
+// validateLink checks that the link is HTTP(S) URL pointing to one of allowed
+// hosts or their subdomains. Any host is accepted when no host is configured.
+func (f *MustGatherForm) validateLink(allowedHosts []string) {
+	if f.Link == "" {
+		return
+	}
+	link, err := url.Parse(f.Link)
+	if err != nil || (link.Scheme != "http" && link.Scheme != "https") || link.Hostname() == "" {
+		f.setError(linkParameter, "Link must be a valid http or https URL")
+		return
+	}
+	if len(allowedHosts) > 0 && !allowedHost(link.Hostname(), allowedHosts) {
+		f.setError(linkParameter, "Link must point to one of: "+strings.Join(allowedHosts, ", "))
+	}
+}
+
+// allowedHost checks whether host is one of allowed hosts or its subdomain
+func allowedHost(host string, allowedHosts []string) bool {
+	host = strings.ToLower(host)
+	for _, allowed := range allowedHosts {
+		allowed = strings.ToLower(allowed)
+		if host == allowed || strings.HasSuffix(host, "."+allowed) {
+			return true
+		}
+	}
+	return false
+}
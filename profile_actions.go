@@ -0,0 +1,213 @@
+/*
+Copyright © 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"github.com/tisnik/insights-operator-web-ui/client"
+	"github.com/tisnik/insights-operator-web-ui/logging"
+	"github.com/tisnik/insights-operator-web-ui/types"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// ProfileFormDynContent represents dynamic part of HTML page with form for
+// new or changed configuration profile.
+//
+//	Profile: profile the form is pre-filled with
+//	Edit: whether existing profile is changed, otherwise new profile is
+//	      created from the form
+type ProfileFormDynContent struct {
+	Profile types.ConfigurationProfile
+	Edit    bool
+}
+
+// DeleteProfileDynContent represents dynamic part of HTML page with
+// confirmation of configuration profile deletion.
+//
+//	Profile: profile to be deleted
+//	Configurations: cluster configurations that use the profile
+//	ActiveConfigurations: number of active configurations using the profile
+type DeleteProfileDynContent struct {
+	Profile              types.ConfigurationProfile   `json:"profile"`
+	Configurations       []types.ClusterConfiguration `json:"configurations"`
+	ActiveConfigurations int                          `json:"active_configurations"`
+}
+
+// newDeleteProfileDynContent selects cluster configurations that refer to
+// the profile by its ID
+func newDeleteProfileDynContent(profile types.ConfigurationProfile, configurations []types.ClusterConfiguration) DeleteProfileDynContent {
+	dynData := DeleteProfileDynContent{
+		Profile:        profile,
+		Configurations: []types.ClusterConfiguration{},
+	}
+
+	profileID := strconv.Itoa(profile.ID)
+	for _, configuration := range configurations {
+		if configuration.Configuration != profileID {
+			continue
+		}
+		dynData.Configurations = append(dynData.Configurations, configuration)
+		if configuration.Active == activeConfiguration {
+			dynData.ActiveConfigurations++
+		}
+	}
+	return dynData
+}
+
+// readProfile reads profile selected by ID in path or query. Not found or
+// error page is rendered when the profile can't be read.
+func readProfile(writer http.ResponseWriter, request *http.Request, profileID string) (*types.ConfigurationProfile, bool) {
+	if profileID == "" {
+		notFound(writer, request)
+		return nil, false
+	}
+
+	profile, err := controller.GetConfigurationProfile(request.Context(), profileID)
+	if err != nil {
+		controllerErrorResponse(writer, request, client.OperationGetConfigurationProfile, err)
+		return nil, false
+	}
+	return profile, true
+}
+
+// newProfile displays form for new configuration profile. When ID of
+// existing profile is specified in the clone parameter, the form is
+// pre-filled by its description and configuration.
+func newProfile(writer http.ResponseWriter, request *http.Request) {
+	dynData := ProfileFormDynContent{}
+
+	cloneID := request.URL.Query().Get(cloneParameter)
+	if cloneID != "" {
+		profile, ok := readProfile(writer, request, cloneID)
+		if !ok {
+			return
+		}
+		dynData.Profile.Description = profile.Description
+		dynData.Profile.Configuration = profile.Configuration
+	}
+
+	renderPage(writer, request, http.StatusOK, "new_profile.html", dynData)
+}
+
+// editProfile displays form for new revision of existing configuration
+// profile
+func editProfile(writer http.ResponseWriter, request *http.Request) {
+	profile, ok := readProfile(writer, request, request.URL.Query().Get(idParameter))
+	if !ok {
+		return
+	}
+
+	dynData := ProfileFormDynContent{Profile: *profile, Edit: true}
+	renderPage(writer, request, http.StatusOK, "new_profile.html", dynData)
+}
+
+func updateProfile(writer http.ResponseWriter, request *http.Request) {
+	err := request.ParseForm()
+	if err != nil {
+		logging.Warn(request.Context(), errorHandlingFormMessage, "error", err)
+		notFoundResponse(writer)
+		return
+	}
+
+	profileID := pathOrFormValue(request, idParameter)
+	if profileID == "" {
+		notFound(writer, request)
+		return
+	}
+	username := formUsername(request)
+	description := request.Form.Get(descriptionParameter)
+	configuration := request.Form.Get(configurationParameter)
+
+	logging.Debug(request.Context(), "Changing configuration profile", "profile", profileID, "username", username, "form", logging.RedactForm(request.Form))
+
+	parameters := map[string]string{descriptionParameter: description, configurationParameter: configuration}
+	err = auditAction(request, client.OperationChangeConfigurationProfile, profileID, parameters, func(ctx context.Context) error {
+		return controller.ChangeConfigurationProfile(ctx, profileID, username, description, configuration)
+	})
+	if err != nil {
+		controllerErrorResponse(writer, request, client.OperationChangeConfigurationProfile, err)
+		return
+	}
+
+	// everything is ok, new revision of the profile has been stored
+	logging.Info(request.Context(), "Configuration profile has been changed", "profile", profileID, "username", username)
+	location := "/describe-configuration?" + configurationParameter + "=" + url.QueryEscape(profileID)
+	actionDone(writer, request, http.StatusOK, location, "Configuration profile has been changed")
+}
+
+// readProfileUsage reads profile selected by ID in path or query together
+// with cluster configurations that use it. Error page is rendered when the
+// data can't be read.
+func readProfileUsage(writer http.ResponseWriter, request *http.Request) (DeleteProfileDynContent, bool) {
+	profile, ok := readProfile(writer, request, pathOrFormValue(request, idParameter))
+	if !ok {
+		return DeleteProfileDynContent{}, false
+	}
+
+	configurations, err := controller.ListClusterConfigurations(request.Context())
+	if err != nil {
+		controllerErrorResponse(writer, request, client.OperationListClusterConfigurations, err)
+		return DeleteProfileDynContent{}, false
+	}
+	return newDeleteProfileDynContent(*profile, configurations), true
+}
+
+// profileConfigurations returns cluster configurations that use the profile
+func profileConfigurations(writer http.ResponseWriter, request *http.Request) {
+	dynData, ok := readProfileUsage(writer, request)
+	if !ok {
+		return
+	}
+	writeJSON(writer, request, http.StatusOK, dynData)
+}
+
+// deleteProfileConfirmation displays profile that is going to be deleted
+// together with cluster configurations that still use it
+func deleteProfileConfirmation(writer http.ResponseWriter, request *http.Request) {
+	dynData, ok := readProfileUsage(writer, request)
+	if !ok {
+		return
+	}
+
+	// NoCache headers
+	for k, v := range noCacheHeaders {
+		writer.Header().Set(k, v)
+	}
+
+	renderPage(writer, request, http.StatusOK, "delete_profile.html", dynData)
+}
+
+func deleteProfile(writer http.ResponseWriter, request *http.Request) {
+	profileID := pathOrFormValue(request, idParameter)
+	if profileID == "" {
+		notFound(writer, request)
+		return
+	}
+	err := auditAction(request, client.OperationDeleteConfigurationProfile, profileID, nil, func(ctx context.Context) error {
+		return controller.DeleteConfigurationProfile(ctx, profileID)
+	})
+	if err != nil {
+		controllerErrorResponse(writer, request, client.OperationDeleteConfigurationProfile, err)
+		return
+	}
+
+	// everything is ok, profile has been deleted
+	logging.Info(request.Context(), "Configuration profile has been deleted", "profile", profileID)
+	actionDone(writer, request, http.StatusOK, listProfilesEndpoint, "Configuration profile has been deleted")
+}
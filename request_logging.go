@@ -0,0 +1,59 @@
+/*
+Copyright © 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"github.com/tisnik/insights-operator-web-ui/logging"
+	"net/http"
+	"time"
+)
+
+// statusRecorder remembers HTTP status written by handler
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+// WriteHeader records the status and passes it to the original writer
+func (recorder *statusRecorder) WriteHeader(status int) {
+	recorder.status = status
+	recorder.ResponseWriter.WriteHeader(status)
+}
+
+// withRequestID assigns ID to each request, it is added to all messages
+// logged while handling the request, returned to the client and sent to the
+// controller. ID sent by client in X-Request-ID header is used if valid.
+func withRequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		requestID := request.Header.Get(logging.RequestIDHeader)
+		if !logging.ValidRequestID(requestID) {
+			requestID = logging.NewRequestID()
+		}
+		writer.Header().Set(logging.RequestIDHeader, requestID)
+		request = request.WithContext(logging.WithRequestID(request.Context(), requestID))
+
+		recorder := &statusRecorder{ResponseWriter: writer, status: http.StatusOK}
+		started := time.Now()
+		next.ServeHTTP(recorder, request)
+
+		logging.Info(request.Context(), "Request handled",
+			"method", request.Method,
+			"path", request.URL.Path,
+			"status", recorder.status,
+			"duration", time.Since(started))
+	})
+}
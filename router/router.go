@@ -0,0 +1,193 @@
+/*
+Copyright © 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package router contains HTTP request router that dispatches requests by
+// path and HTTP method. Path patterns can contain parameters written as
+// {name} that match exactly one path segment, for example
+// /clusters/{name}/triggers.
+package router
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Route represents one registered path pattern with its handler and allowed
+// HTTP methods
+type Route struct {
+	pattern  string
+	segments []string
+	methods  []string
+	handler  http.Handler
+}
+
+// With wraps the route handler by given middleware, for example by check
+// that the user is allowed to access the endpoint. Middleware is applied in
+// order, so the last one is called first.
+func (route *Route) With(middleware ...func(http.Handler) http.Handler) *Route {
+	for _, m := range middleware {
+		route.handler = m(route.handler)
+	}
+	return route
+}
+
+// Pattern returns path pattern the route has been registered for
+func (route *Route) Pattern() string {
+	return route.pattern
+}
+
+// allows checks whether the route accepts given HTTP method. HEAD is accepted
+// by all routes that accept GET.
+func (route *Route) allows(method string) bool {
+	for _, allowed := range route.methods {
+		if allowed == method || (allowed == http.MethodGet && method == http.MethodHead) {
+			return true
+		}
+	}
+	return false
+}
+
+// match checks whether the path matches the route pattern and returns values
+// of all path parameters
+func (route *Route) match(segments []string) (map[string]string, bool) {
+	if len(segments) != len(route.segments) {
+		return nil, false
+	}
+
+	params := map[string]string{}
+	for i, segment := range route.segments {
+		if isParameter(segment) {
+			if segments[i] == "" {
+				return nil, false
+			}
+			params[segment[1:len(segment)-1]] = segments[i]
+		} else if segment != segments[i] {
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+// Router dispatches requests to handlers registered for path patterns and
+// HTTP methods. It responds with 404 Not Found when no pattern matches the
+// path and with 405 Method Not Allowed when the method is not accepted.
+type Router struct {
+	routes []*Route
+
+	// NotFound is called when no route matches the request path, if nil
+	// http.NotFound is used
+	NotFound http.Handler
+}
+
+// New constructs new router without any routes
+func New() *Router {
+	return &Router{}
+}
+
+// Handle registers handler for given path pattern and HTTP methods
+func (router *Router) Handle(pattern string, handler http.Handler, methods ...string) *Route {
+	route := &Route{
+		pattern:  pattern,
+		segments: splitPath(pattern),
+		methods:  methods,
+		handler:  handler,
+	}
+	router.routes = append(router.routes, route)
+	return route
+}
+
+// HandleFunc registers handler function for given path pattern and HTTP
+// methods
+func (router *Router) HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request), methods ...string) *Route {
+	return router.Handle(pattern, http.HandlerFunc(handler), methods...)
+}
+
+// Routes returns all registered routes in order they have been registered
+func (router *Router) Routes() []*Route {
+	return router.routes
+}
+
+// ServeHTTP dispatches the request to handler of the first matching route
+func (router *Router) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	segments := splitPath(request.URL.Path)
+	var allowed []string
+
+	for _, route := range router.routes {
+		params, matched := route.match(segments)
+		if !matched {
+			continue
+		}
+		if !route.allows(request.Method) {
+			allowed = append(allowed, route.methods...)
+			continue
+		}
+		ctx := context.WithValue(request.Context(), paramsKey{}, params)
+		route.handler.ServeHTTP(writer, request.WithContext(ctx))
+		return
+	}
+
+	if len(allowed) > 0 {
+		writer.Header().Set("Allow", allowHeader(allowed))
+		http.Error(writer, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	if router.NotFound != nil {
+		router.NotFound.ServeHTTP(writer, request)
+		return
+	}
+	http.NotFound(writer, request)
+}
+
+type paramsKey struct{}
+
+// Param returns value of path parameter with given name or empty string when
+// the parameter does not exist
+func Param(request *http.Request, name string) string {
+	params, ok := request.Context().Value(paramsKey{}).(map[string]string)
+	if !ok {
+		return ""
+	}
+	return params[name]
+}
+
+func isParameter(segment string) bool {
+	return len(segment) > 2 && strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}")
+}
+
+func splitPath(path string) []string {
+	return strings.Split(strings.Trim(path, "/"), "/")
+}
+
+// allowHeader constructs value of Allow header from list of methods
+func allowHeader(methods []string) string {
+	unique := map[string]bool{}
+	for _, method := range methods {
+		unique[method] = true
+		if method == http.MethodGet {
+			unique[http.MethodHead] = true
+		}
+	}
+
+	result := make([]string, 0, len(unique))
+	for method := range unique {
+		result = append(result, method)
+	}
+	sort.Strings(result)
+	return strings.Join(result, ", ")
+}
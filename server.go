@@ -0,0 +1,172 @@
+/*
+Copyright © 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"github.com/tisnik/insights-operator-web-ui/logging"
+	"net/http"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// Default values used for HTTP server settings that are not specified
+const (
+	defaultReadTimeout         = 10 * time.Second
+	defaultReadHeaderTimeout   = 5 * time.Second
+	defaultWriteTimeout        = 60 * time.Second
+	defaultIdleTimeout         = 120 * time.Second
+	defaultShutdownGracePeriod = 30 * time.Second
+)
+
+// ServerConfiguration represents configuration of the HTTP server.
+//
+//	Address: port or full address where the service will be available
+//	ReadTimeout: maximum duration for reading the entire request
+//	ReadHeaderTimeout: maximum duration for reading request headers
+//	WriteTimeout: maximum duration before timing out writes of the response
+//	IdleTimeout: maximum time to wait for the next request on keep-alive connection
+//	ShutdownGracePeriod: time given to in-flight requests to finish on shutdown
+//	TLS: configuration of TLS termination
+type ServerConfiguration struct {
+	Address             string
+	ReadTimeout         time.Duration
+	ReadHeaderTimeout   time.Duration
+	WriteTimeout        time.Duration
+	IdleTimeout         time.Duration
+	ShutdownGracePeriod time.Duration
+	TLS                 TLSConfiguration
+}
+
+// durationOrDefault returns default value for durations that are not set
+func durationOrDefault(duration time.Duration, defaultValue time.Duration) time.Duration {
+	if duration <= 0 {
+		return defaultValue
+	}
+	return duration
+}
+
+// newHTTPServer constructs HTTP server with configured timeouts
+func newHTTPServer(configuration ServerConfiguration, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              configuration.Address,
+		Handler:           handler,
+		ReadTimeout:       durationOrDefault(configuration.ReadTimeout, defaultReadTimeout),
+		ReadHeaderTimeout: durationOrDefault(configuration.ReadHeaderTimeout, defaultReadHeaderTimeout),
+		WriteTimeout:      durationOrDefault(configuration.WriteTimeout, defaultWriteTimeout),
+		IdleTimeout:       durationOrDefault(configuration.IdleTimeout, defaultIdleTimeout),
+	}
+}
+
+// listen starts accepting connections on server address. TLS is used when
+// the server has TLS configuration.
+func listen(server *http.Server, serverErrors chan<- error) {
+	var err error
+	if server.TLSConfig != nil {
+		logging.Info(context.Background(), "Starting the service", "address", server.Addr, "tls", true)
+		err = server.ListenAndServeTLS("", "")
+	} else {
+		logging.Info(context.Background(), "Starting the service", "address", server.Addr, "tls", false)
+		err = server.ListenAndServe()
+	}
+	serverErrors <- err
+}
+
+// newServers constructs the main HTTP server and optionally the server that
+// redirects plain HTTP requests to HTTPS
+func newServers(configuration ServerConfiguration) ([]*http.Server, error) {
+	server := newHTTPServer(configuration, newHandler())
+	if !configuration.TLS.Enabled {
+		return []*http.Server{server}, nil
+	}
+
+	tlsConfig, err := newTLSConfig(configuration.TLS)
+	if err != nil {
+		return nil, err
+	}
+	server.TLSConfig = tlsConfig
+	servers := []*http.Server{server}
+
+	if configuration.TLS.RedirectAddress != "" {
+		redirectConfiguration := configuration
+		redirectConfiguration.Address = configuration.TLS.RedirectAddress
+		redirectServer := newHTTPServer(redirectConfiguration, httpsRedirect(configuration.Address))
+		servers = append(servers, redirectServer)
+	}
+	return servers, nil
+}
+
+// startHTTPServer starts the HTTP server(s) and blocks until SIGTERM or
+// SIGINT is received. Then the servers stop accepting new connections and
+// wait for in-flight requests to finish within the configured grace period.
+func startHTTPServer(configuration ServerConfiguration) error {
+	servers, err := newServers(configuration)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
+
+	serverErrors := make(chan error, len(servers))
+	for _, server := range servers {
+		go listen(server, serverErrors)
+	}
+
+	select {
+	case err := <-serverErrors:
+		shutdown(servers, configuration.ShutdownGracePeriod)
+		return err
+	case <-ctx.Done():
+		stop()
+	}
+
+	err = shutdown(servers, configuration.ShutdownGracePeriod)
+	if err != nil {
+		return err
+	}
+
+	for range servers {
+		err = <-serverErrors
+		if !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+	}
+	logging.Info(context.Background(), "Service has been stopped")
+	return nil
+}
+
+// shutdown stops all servers gracefully within given grace period
+func shutdown(servers []*http.Server, gracePeriod time.Duration) error {
+	gracePeriod = durationOrDefault(gracePeriod, defaultShutdownGracePeriod)
+	logging.Info(context.Background(), "Shutting down the service, waiting for in-flight requests", "grace_period", gracePeriod)
+
+	ctx, cancel := context.WithTimeout(context.Background(), gracePeriod)
+	defer cancel()
+
+	var result error
+	for _, server := range servers {
+		err := server.Shutdown(ctx)
+		if err != nil {
+			logging.Error(ctx, "Error shutting down server", "address", server.Addr, "error", err)
+			result = err
+		}
+	}
+	return result
+}
@@ -0,0 +1,285 @@
+/*
+Copyright © 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/tisnik/insights-operator-web-ui/auth"
+	"github.com/tisnik/insights-operator-web-ui/client"
+	"github.com/tisnik/insights-operator-web-ui/logging"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"path"
+	"sync"
+	"time"
+)
+
+// Base layout shared by all pages. The layout contains the HTML head,
+// navigation bar and footer, pages define "title", "content" and optionally
+// "head" blocks.
+const (
+	layoutTemplate     = "layout.html"
+	layoutTemplateName = "layout"
+)
+
+// templatesCheckInterval is how often files are checked for changes when
+// hot reload is enabled
+const templatesCheckInterval = time.Second
+
+// RequestFuncs returns template functions whose results depend on the
+// request being served, for example name of the logged in user. It is called
+// with nil request when templates are parsed, so all function names are
+// known to the parser.
+type RequestFuncs func(request *http.Request) template.FuncMap
+
+// TemplateRegistry contains all page templates parsed at startup. When hot
+// reload is enabled, templates are parsed again when any file used by the
+// template changes on disk. Files are checked at most once per
+// templatesCheckInterval.
+type TemplateRegistry struct {
+	assets    fs.FS
+	hotReload bool
+	funcs     RequestFuncs
+
+	mutex     sync.RWMutex
+	templates map[string]*template.Template
+
+	// modTime is modification time of the newest file seen, including
+	// files that failed to parse, so a broken template is reported once
+	modTime   time.Time
+	checkedAt time.Time
+}
+
+// templates is the registry used by all handlers
+var templates *TemplateRegistry
+
+// NewTemplateRegistry parses layout and all pages found in given file system.
+// An error is returned when any template is broken.
+func NewTemplateRegistry(assets fs.FS, hotReload bool, funcs RequestFuncs) (*TemplateRegistry, error) {
+	registry := &TemplateRegistry{
+		assets:    assets,
+		hotReload: hotReload,
+		funcs:     funcs,
+		modTime:   newestModTime(assets),
+		checkedAt: time.Now(),
+	}
+
+	err := registry.parseAll()
+	if err != nil {
+		return nil, err
+	}
+	return registry, nil
+}
+
+// pageNames returns names of all pages, i.e. all HTML files except layout
+func (r *TemplateRegistry) pageNames() ([]string, error) {
+	names, err := fs.Glob(r.assets, "*.html")
+	if err != nil {
+		return nil, err
+	}
+
+	pages := make([]string, 0, len(names))
+	for _, name := range names {
+		if name != layoutTemplate {
+			pages = append(pages, name)
+		}
+	}
+	return pages, nil
+}
+
+// parseAll parses all page templates together with the layout
+func (r *TemplateRegistry) parseAll() error {
+	pages, err := r.pageNames()
+	if err != nil {
+		return err
+	}
+
+	parsed := make(map[string]*template.Template, len(pages))
+	for _, page := range pages {
+		t, err := template.New(page).Funcs(r.funcs(nil)).ParseFS(r.assets, layoutTemplate, page)
+		if err != nil {
+			return fmt.Errorf("Error parsing template %s: %v", page, err)
+		}
+		if t.Lookup("content") == nil {
+			return fmt.Errorf("Template %s does not define content block", page)
+		}
+		parsed[page] = t
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.templates = parsed
+	logging.Info(context.Background(), "Templates parsed", "count", len(parsed))
+	return nil
+}
+
+// newestModTime returns modification time of the newest HTML file
+func newestModTime(assets fs.FS) time.Time {
+	var newest time.Time
+	names, err := fs.Glob(assets, "*.html")
+	if err != nil {
+		return newest
+	}
+
+	for _, name := range names {
+		info, err := fs.Stat(assets, name)
+		if err == nil && info.ModTime().After(newest) {
+			newest = info.ModTime()
+		}
+	}
+	return newest
+}
+
+// changedOnDisk checks whether any HTML file has been modified since the
+// last check. Nothing is checked when the previous check was made less than
+// templatesCheckInterval ago.
+func (r *TemplateRegistry) changedOnDisk() bool {
+	r.mutex.Lock()
+	now := time.Now()
+	due := now.Sub(r.checkedAt) >= templatesCheckInterval
+	if due {
+		r.checkedAt = now
+	}
+	r.mutex.Unlock()
+	if !due {
+		return false
+	}
+
+	// files are read without lock, so pages can be rendered meanwhile
+	modTime := newestModTime(r.assets)
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if !modTime.After(r.modTime) {
+		return false
+	}
+	r.modTime = modTime
+	return true
+}
+
+// reloadIfChanged parses templates again when hot reload is enabled and some
+// file has been changed. Broken templates are reported, but the previously
+// parsed ones are kept until the files change again.
+func (r *TemplateRegistry) reloadIfChanged() {
+	if !r.hotReload || !r.changedOnDisk() {
+		return
+	}
+	logging.Info(context.Background(), "Templates changed on disk, reloading")
+	err := r.parseAll()
+	if err != nil {
+		logging.Error(context.Background(), "Unable to reload templates", "error", err)
+	}
+}
+
+// Count returns number of parsed page templates
+func (r *TemplateRegistry) Count() int {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return len(r.templates)
+}
+
+// lookup returns template for given page
+func (r *TemplateRegistry) lookup(page string) (*template.Template, error) {
+	r.reloadIfChanged()
+
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	t, found := r.templates[path.Clean(page)]
+	if !found {
+		return nil, fmt.Errorf("Template %s not found", page)
+	}
+	return t, nil
+}
+
+// Render executes template for given page and writes the result with given
+// HTTP status code. The page is rendered into buffer first, so nothing is
+// written when template execution fails. Parsed templates are never executed
+// directly, the copy with functions bound to the request is used instead.
+func (r *TemplateRegistry) Render(writer http.ResponseWriter, request *http.Request, status int, page string, data interface{}) error {
+	buffer, err := r.execute(request, page, data)
+	if err != nil {
+		templateRenderErrors.Inc(page)
+		return err
+	}
+
+	writer.Header().Set("Content-Type", ContentTypeHTML)
+	writer.WriteHeader(status)
+	_, err = buffer.WriteTo(writer)
+	return err
+}
+
+// execute renders the page into buffer
+func (r *TemplateRegistry) execute(request *http.Request, page string, data interface{}) (*bytes.Buffer, error) {
+	parsed, err := r.lookup(page)
+	if err != nil {
+		return nil, err
+	}
+
+	t, err := parsed.Clone()
+	if err != nil {
+		return nil, err
+	}
+	t.Funcs(r.funcs(request))
+
+	var buffer bytes.Buffer
+	err = t.ExecuteTemplate(&buffer, layoutTemplateName, data)
+	if err != nil {
+		return nil, err
+	}
+	return &buffer, nil
+}
+
+// templateFuncs returns functions available in all page templates
+func templateFuncs(request *http.Request) template.FuncMap {
+	return template.FuncMap{
+		"currentUser": func() string {
+			return currentUsername(request)
+		},
+		"csrfField": func() template.HTML {
+			return csrfField(request)
+		},
+		"can": func(permission string) bool {
+			return request != nil && allowed(request, auth.Permission(permission))
+		},
+		"formatURL": func(format string) string {
+			return formatURL(request, format)
+		},
+		"pageURL": func(page int) string {
+			return pageURL(request, page)
+		},
+		"sortHeader": func(column string, label string) template.HTML {
+			return sortHeader(request, column, label)
+		},
+		"pageSizes": func() []int {
+			return pageSizes
+		},
+		"staleSince": func() string {
+			if request == nil {
+				return ""
+			}
+			readAt := client.StaleSince(request.Context())
+			if readAt.IsZero() {
+				return ""
+			}
+			return readAt.Format(time.RFC1123)
+		},
+	}
+}
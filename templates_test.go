@@ -0,0 +1,105 @@
+/*
+Copyright © 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"github.com/tisnik/insights-operator-web-ui/logging"
+	"html/template"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+const testLayout = `{{define "layout"}}<html>{{template "content" .}}</html>{{end}}`
+
+func noTemplateFuncs(request *http.Request) template.FuncMap {
+	return template.FuncMap{}
+}
+
+// renderTestPage renders page and returns the output
+func renderTestPage(t *testing.T, registry *TemplateRegistry, page string) string {
+	t.Helper()
+	buffer, err := registry.execute(nil, page, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buffer.String()
+}
+
+// TestTemplatesHotReload checks that changed templates are parsed again and
+// that broken template is reported once while the old one is still used
+func TestTemplatesHotReload(t *testing.T) {
+	var output bytes.Buffer
+	logging.SetOutput(&output)
+	defer logging.SetOutput(os.Stderr)
+
+	modTime := time.Date(2022, 5, 1, 10, 0, 0, 0, time.UTC)
+	assets := fstest.MapFS{
+		layoutTemplate: {Data: []byte(testLayout), ModTime: modTime},
+		"page.html":    {Data: []byte(`{{define "content"}}first{{end}}`), ModTime: modTime},
+	}
+	registry, err := NewTemplateRegistry(assets, true, noTemplateFuncs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// files are not checked again within the interval
+	assets["page.html"] = &fstest.MapFile{Data: []byte(`{{define "content"}}second{{end}}`), ModTime: modTime.Add(time.Minute)}
+	if page := renderTestPage(t, registry, "page.html"); page != "<html>first</html>" {
+		t.Errorf("unexpected page %q before check interval elapsed", page)
+	}
+
+	registry.checkedAt = time.Time{}
+	if page := renderTestPage(t, registry, "page.html"); page != "<html>second</html>" {
+		t.Errorf("unexpected page %q after change", page)
+	}
+
+	assets["page.html"] = &fstest.MapFile{Data: []byte(`{{define "content"}}{{broken}}{{end}}`), ModTime: modTime.Add(2 * time.Minute)}
+	for i := 0; i < 3; i++ {
+		registry.checkedAt = time.Time{}
+		if page := renderTestPage(t, registry, "page.html"); page != "<html>second</html>" {
+			t.Errorf("unexpected page %q after broken change", page)
+		}
+	}
+	if count := strings.Count(output.String(), "Unable to reload templates"); count != 1 {
+		t.Errorf("broken template reported %d times, expected once", count)
+	}
+}
+
+// TestTemplatesWithoutHotReload checks that files are never read again
+// when hot reload is disabled
+func TestTemplatesWithoutHotReload(t *testing.T) {
+	modTime := time.Date(2022, 5, 1, 10, 0, 0, 0, time.UTC)
+	assets := fstest.MapFS{
+		layoutTemplate: {Data: []byte(testLayout), ModTime: modTime},
+		"page.html":    {Data: []byte(`{{define "content"}}first{{end}}`), ModTime: modTime},
+	}
+	registry, err := NewTemplateRegistry(assets, false, noTemplateFuncs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assets["page.html"] = &fstest.MapFile{Data: []byte(`{{define "content"}}second{{end}}`), ModTime: modTime.Add(time.Minute)}
+	registry.checkedAt = time.Time{}
+	if page := renderTestPage(t, registry, "page.html"); page != "<html>first</html>" {
+		t.Errorf("unexpected page %q", page)
+	}
+}
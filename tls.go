@@ -0,0 +1,186 @@
+/*
+Copyright © 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"github.com/tisnik/insights-operator-web-ui/logging"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// TLS version used when minimal version is not configured
+const defaultTLSMinVersion = "1.2"
+
+// supported values of minimal TLS version option
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// TLSConfiguration represents configuration of TLS termination.
+//
+//	Enabled: flag indicating whether the service listens over HTTPS
+//	CertFile: file with server certificate (PEM), may contain full chain
+//	KeyFile: file with private key for the certificate (PEM)
+//	MinVersion: minimal TLS version accepted, for example "1.2"
+//	RedirectAddress: address of plain HTTP listener that redirects all
+//	                 requests to HTTPS, empty to disable the listener
+type TLSConfiguration struct {
+	Enabled         bool
+	CertFile        string
+	KeyFile         string
+	MinVersion      string
+	RedirectAddress string
+}
+
+// certificateReloader provides server certificate for TLS handshakes. The
+// certificate is loaded again when certificate or key file changes on disk,
+// so rotated certificates are used without restarting the service. Files are
+// loaded once for each change, broken files are not read again on every
+// handshake.
+type certificateReloader struct {
+	certFile string
+	keyFile  string
+
+	mutex       sync.RWMutex
+	certificate *tls.Certificate
+	modTime     time.Time
+}
+
+// newCertificateReloader loads certificate and key from given files
+func newCertificateReloader(certFile string, keyFile string) (*certificateReloader, error) {
+	reloader := &certificateReloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+	}
+	reloader.modTime = reloader.filesModTime()
+	err := reloader.load()
+	if err != nil {
+		return nil, err
+	}
+	return reloader, nil
+}
+
+func (r *certificateReloader) load() error {
+	certificate, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("Unable to load certificate %s and key %s: %v", r.certFile, r.keyFile, err)
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.certificate = &certificate
+	return nil
+}
+
+// filesModTime returns the newest modification time of certificate and key
+// files
+func (r *certificateReloader) filesModTime() time.Time {
+	var modTime time.Time
+	for _, filename := range []string{r.certFile, r.keyFile} {
+		info, err := os.Stat(filename)
+		if err == nil && info.ModTime().After(modTime) {
+			modTime = info.ModTime()
+		}
+	}
+	return modTime
+}
+
+// changedOnDisk checks whether certificate or key file has been modified
+// since the last attempt to load them. The modification time is recorded
+// even when the files can't be loaded, so they are read again only after
+// the next change.
+func (r *certificateReloader) changedOnDisk() bool {
+	modTime := r.filesModTime()
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if !modTime.After(r.modTime) {
+		return false
+	}
+	r.modTime = modTime
+	return true
+}
+
+// GetCertificate returns the current certificate, it is meant to be used as
+// tls.Config.GetCertificate callback. When the new certificate can't be
+// loaded (for example when only one of files has been replaced so far), the
+// previous one is used.
+func (r *certificateReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	if r.changedOnDisk() {
+		logging.Info(context.Background(), "Certificate changed on disk, reloading")
+		err := r.load()
+		if err != nil {
+			logging.Error(context.Background(), "Unable to reload certificate", "error", err)
+		}
+	}
+
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return r.certificate, nil
+}
+
+// newTLSConfig constructs TLS configuration for the HTTP server
+func newTLSConfig(configuration TLSConfiguration) (*tls.Config, error) {
+	minVersion := configuration.MinVersion
+	if minVersion == "" {
+		minVersion = defaultTLSMinVersion
+	}
+	version, found := tlsVersions[minVersion]
+	if !found {
+		return nil, fmt.Errorf("Unsupported minimal TLS version '%s'", minVersion)
+	}
+
+	reloader, err := newCertificateReloader(configuration.CertFile, configuration.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tls.Config{
+		MinVersion:     version,
+		GetCertificate: reloader.GetCertificate,
+	}, nil
+}
+
+// httpsRedirect returns handler that redirects all requests to the same URL
+// on HTTPS listener available on given address
+func httpsRedirect(httpsAddress string) http.Handler {
+	_, port, err := net.SplitHostPort(httpsAddress)
+	if err != nil || port == "443" {
+		port = ""
+	}
+
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		host, _, err := net.SplitHostPort(request.Host)
+		if err != nil {
+			host = request.Host
+		}
+		if port != "" {
+			host = net.JoinHostPort(host, port)
+		}
+		target := "https://" + host + request.URL.RequestURI()
+		http.Redirect(writer, request, target, http.StatusPermanentRedirect)
+	})
+}
@@ -0,0 +1,111 @@
+/*
+Copyright © 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeCertificate writes new self-signed certificate and its key to given
+// files and sets their modification time
+func writeCertificate(t *testing.T, certFile string, keyFile string, modTime time.Time) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(modTime.UnixNano()),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		NotBefore:    modTime,
+		NotAfter:     modTime.Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writeFile(t, certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), modTime)
+	writeFile(t, keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), modTime)
+}
+
+// writeFile writes content to file and sets its modification time
+func writeFile(t *testing.T, filename string, content []byte, modTime time.Time) {
+	t.Helper()
+	err := ioutil.WriteFile(filename, content, 0600)
+	if err == nil {
+		err = os.Chtimes(filename, modTime, modTime)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestCertificateReloader checks that changed certificate is loaded once for
+// each change and the previous certificate is used while files are broken
+func TestCertificateReloader(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	start := time.Now().Add(-time.Hour)
+
+	writeCertificate(t, certFile, keyFile, start)
+	reloader, err := newCertificateReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	original, _ := reloader.GetCertificate(nil)
+	if reloader.changedOnDisk() {
+		t.Fatal("unchanged files reported as changed")
+	}
+
+	// only certificate has been replaced so far, it does not match the key
+	writeCertificate(t, certFile, filepath.Join(dir, "other.pem"), start.Add(time.Minute))
+	if current, _ := reloader.GetCertificate(nil); current != original {
+		t.Error("previous certificate should be used while files are broken")
+	}
+	if reloader.changedOnDisk() {
+		t.Error("broken files should be loaded only once for each change")
+	}
+
+	writeCertificate(t, certFile, keyFile, start.Add(2*time.Minute))
+	if current, _ := reloader.GetCertificate(nil); current == original {
+		t.Error("new certificate not loaded")
+	}
+	if reloader.changedOnDisk() {
+		t.Error("loaded files reported as changed")
+	}
+}
@@ -0,0 +1,178 @@
+/*
+Copyright © 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/tisnik/insights-operator-web-ui/auth"
+	"github.com/tisnik/insights-operator-web-ui/logging"
+	"github.com/tisnik/insights-operator-web-ui/router"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// uiAPIPrefix is prepended to all endpoints of JSON API. The API is served
+// by the same handlers as HTML pages, only the response format differs.
+const uiAPIPrefix = "/ui-api/v1"
+
+// maximal size of JSON request body
+const maxJSONBodySize = 1024 * 1024
+
+// ActionResult is returned by API endpoints that change state.
+//
+//	Status: always ok, errors are returned as ErrorPageDynContent
+//	Message: description of the performed action
+type ActionResult struct {
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
+
+// isAPIRequest checks whether the request has been sent to JSON API
+func isAPIRequest(request *http.Request) bool {
+	return request.URL.Path == uiAPIPrefix || strings.HasPrefix(request.URL.Path, uiAPIPrefix+"/")
+}
+
+// writeJSON writes data as JSON with given HTTP status code
+func writeJSON(writer http.ResponseWriter, request *http.Request, status int, data interface{}) {
+	body, err := json.Marshal(data)
+	if err != nil {
+		logging.Error(request.Context(), "Unable to serialize response", "error", err)
+		http.Error(writer, "Unable to serialize response", http.StatusInternalServerError)
+		return
+	}
+
+	writer.Header().Set("Content-Type", ContentTypeJSON)
+	writer.WriteHeader(status)
+	_, err = writer.Write(append(body, '\n'))
+	if err != nil {
+		logging.Warn(request.Context(), "Error sending response", "error", err)
+	}
+}
+
+// actionDone finishes successful state changing request. Browser is
+// redirected to the given page, API client gets JSON with the message.
+func actionDone(writer http.ResponseWriter, request *http.Request, status int, location string, message string) {
+	if isAPIRequest(request) {
+		writeJSON(writer, request, status, ActionResult{Status: "ok", Message: message})
+		return
+	}
+	http.Redirect(writer, request, location, http.StatusSeeOther)
+}
+
+// pathOrFormValue returns value of path parameter, or value of form field
+// with the same name when the route has no such parameter. HTML forms send
+// IDs in form fields, API has them in path.
+func pathOrFormValue(request *http.Request, name string) string {
+	value := router.Param(request, name)
+	if value == "" {
+		value = request.FormValue(name)
+	}
+	return value
+}
+
+// jsonFieldsToForm converts fields of JSON object to form values. All fields
+// need to be strings, null fields are skipped.
+func jsonFieldsToForm(fields map[string]interface{}) (url.Values, error) {
+	form := url.Values{}
+	for name, value := range fields {
+		switch value := value.(type) {
+		case nil:
+			continue
+		case string:
+			form.Set(name, value)
+		default:
+			return nil, fmt.Errorf("Value of field %q needs to be a string", name)
+		}
+	}
+	return form, nil
+}
+
+// decodeJSONBody converts JSON object sent in request body to form values,
+// so handlers read API requests the same way as submitted HTML forms.
+// Requests with other content types are passed unchanged.
+func decodeJSONBody(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		mediaType, _, _ := mime.ParseMediaType(request.Header.Get("Content-Type"))
+		if mediaType != ContentTypeJSON || request.Body == nil {
+			next.ServeHTTP(writer, request)
+			return
+		}
+
+		var fields map[string]interface{}
+		decoder := json.NewDecoder(http.MaxBytesReader(writer, request.Body, maxJSONBodySize))
+		err := decoder.Decode(&fields)
+		if err != nil {
+			logging.Warn(request.Context(), errorHandlingFormMessage, "error", err)
+			errorPageResponse(writer, request, http.StatusBadRequest, errorPageTemplate, ErrorPageDynContent{
+				Title:     "Invalid request body",
+				Operation: request.Method + " " + request.URL.Path,
+				Message:   "Request body needs to be JSON object: " + err.Error(),
+			})
+			return
+		}
+
+		form, err := jsonFieldsToForm(fields)
+		if err != nil {
+			logging.Warn(request.Context(), errorHandlingFormMessage, "error", err)
+			errorPageResponse(writer, request, http.StatusBadRequest, errorPageTemplate, ErrorPageDynContent{
+				Title:     "Invalid request body",
+				Operation: request.Method + " " + request.URL.Path,
+				Message:   err.Error(),
+			})
+			return
+		}
+		request.PostForm = form
+		request.Form = form
+		next.ServeHTTP(writer, request)
+	})
+}
+
+// registerUIAPI registers endpoints of JSON API. The same handlers and
+// permissions are used as for HTML pages.
+func registerUIAPI(r *router.Router) {
+	canRead := requirePermission(auth.PermissionRead)
+	canWrite := requirePermission(auth.PermissionWrite)
+	canMustGather := requirePermission(auth.PermissionMustGather)
+	canApproveMustGather := requirePermission(auth.PermissionApproveMustGather)
+
+	r.HandleFunc(uiAPIPrefix+"/openapi.json", staticPage("openapi.json"), http.MethodGet)
+	r.HandleFunc(uiAPIPrefix+"/clusters", listClusters, http.MethodGet).With(canRead)
+	r.HandleFunc(uiAPIPrefix+"/clusters/{name}", clusterDetail, http.MethodGet).With(canRead)
+	r.HandleFunc(uiAPIPrefix+"/clusters/{name}/triggers", listTriggers, http.MethodGet).With(canRead)
+	r.HandleFunc(uiAPIPrefix+"/profiles", listProfiles, http.MethodGet).With(canRead)
+	r.HandleFunc(uiAPIPrefix+"/profiles", storeProfile, http.MethodPost).With(decodeJSONBody, canWrite)
+	r.HandleFunc(uiAPIPrefix+"/profiles/{id}", describeConfiguration, http.MethodGet).With(canRead)
+	r.HandleFunc(uiAPIPrefix+"/profiles/{id}", updateProfile, http.MethodPut).With(decodeJSONBody, canWrite)
+	r.HandleFunc(uiAPIPrefix+"/profiles/{id}", deleteProfile, http.MethodDelete).With(canWrite)
+	r.HandleFunc(uiAPIPrefix+"/profiles/{id}/configurations", profileConfigurations, http.MethodGet).With(canRead)
+	r.HandleFunc(uiAPIPrefix+"/configurations", listConfigurations, http.MethodGet).With(canRead)
+	r.HandleFunc(uiAPIPrefix+"/configurations", storeConfiguration, http.MethodPost).With(decodeJSONBody, canWrite)
+	r.HandleFunc(uiAPIPrefix+"/configurations/{id}/enable", enableConfiguration, http.MethodPost).With(canWrite)
+	r.HandleFunc(uiAPIPrefix+"/configurations/{id}/disable", disableConfiguration, http.MethodPost).With(canWrite)
+	r.HandleFunc(uiAPIPrefix+"/triggers", listTriggers, http.MethodGet).With(canRead)
+	r.HandleFunc(uiAPIPrefix+"/triggers/{id}/activate", activateTrigger, http.MethodPost).With(canWrite)
+	r.HandleFunc(uiAPIPrefix+"/triggers/{id}/deactivate", deactivateTrigger, http.MethodPost).With(canWrite)
+	r.HandleFunc(uiAPIPrefix+"/must-gather", triggerMustGather, http.MethodPost).With(decodeJSONBody, canMustGather)
+	r.HandleFunc(uiAPIPrefix+"/must-gather-requests", mustGatherRequestsPage, http.MethodGet).With(canRead)
+	r.HandleFunc(uiAPIPrefix+"/must-gather-requests/{id}", mustGatherRequestPage, http.MethodGet).With(canRead)
+	r.HandleFunc(uiAPIPrefix+"/must-gather-requests/{id}/approve", approveMustGather, http.MethodPost).With(decodeJSONBody, canApproveMustGather)
+	r.HandleFunc(uiAPIPrefix+"/must-gather-requests/{id}/reject", rejectMustGather, http.MethodPost).With(decodeJSONBody, canApproveMustGather)
+	r.HandleFunc(uiAPIPrefix+"/must-gather-requests/{id}/cancel", cancelMustGather, http.MethodPost).With(decodeJSONBody, canMustGather)
+}
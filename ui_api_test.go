@@ -0,0 +1,65 @@
+/*
+Copyright © 2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestDecodeJSONBody checks that JSON object with string values is
+// converted to form and other bodies are refused
+func TestDecodeJSONBody(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		status  int
+		reason  string
+		missing bool
+	}{
+		{name: "string values", body: `{"reason": "crash loop", "link": "https://issues.redhat.com/1"}`, status: http.StatusOK, reason: "crash loop"},
+		{name: "null value is skipped", body: `{"reason": null}`, status: http.StatusOK, missing: true},
+		{name: "number", body: `{"reason": 12345678901234567890}`, status: http.StatusBadRequest},
+		{name: "boolean", body: `{"reason": true}`, status: http.StatusBadRequest},
+		{name: "object", body: `{"reason": {"text": "crash loop"}}`, status: http.StatusBadRequest},
+		{name: "array", body: `{"reason": ["crash", "loop"]}`, status: http.StatusBadRequest},
+		{name: "not an object", body: `["crash loop"]`, status: http.StatusBadRequest},
+		{name: "invalid JSON", body: `{"reason": `, status: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := decodeJSONBody(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+				_, found := request.Form[reasonParameter]
+				if found == tt.missing || request.FormValue(reasonParameter) != tt.reason {
+					t.Errorf("unexpected form %v", request.Form)
+				}
+			}))
+
+			request := httptest.NewRequest(http.MethodPost, uiAPIPrefix+"/must-gather", strings.NewReader(tt.body))
+			request.Header.Set("Content-Type", ContentTypeJSON)
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+
+			if recorder.Code != tt.status {
+				t.Errorf("expected status %d, got %d: %s", tt.status, recorder.Code, recorder.Body.String())
+			}
+		})
+	}
+}
@@ -17,16 +17,19 @@ limitations under the License.
 package main
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
 	"github.com/spf13/viper"
+	"github.com/tisnik/insights-operator-web-ui/approval"
+	"github.com/tisnik/insights-operator-web-ui/auth"
+	"github.com/tisnik/insights-operator-web-ui/client"
+	"github.com/tisnik/insights-operator-web-ui/listing"
+	"github.com/tisnik/insights-operator-web-ui/logging"
+	"github.com/tisnik/insights-operator-web-ui/router"
 	"github.com/tisnik/insights-operator-web-ui/types"
-	"html/template"
-	"io"
-	"io/ioutil"
+	"io/fs"
 	"log"
 	"net/http"
-	"net/url"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -46,16 +49,22 @@ const (
 
 	// ContentTypeCSS represents content type text/css used in HTTP responses
 	ContentTypeCSS = "text/css"
+
+	// ContentTypeJSON represents content type application/json used in HTTP responses
+	ContentTypeJSON = "application/json"
 )
 
 // URL and form parameters
 const (
+	idParameter            = "id"
 	usernameParameter      = "username"
 	linkParameter          = "link"
 	reasonParameter        = "reason"
 	clusterParameter       = "cluster"
 	descriptionParameter   = "description"
 	configurationParameter = "configuration"
+	requestedByParameter   = "requested_by"
+	cloneParameter         = "clone"
 )
 
 // REST API endpoints
@@ -65,9 +74,12 @@ const (
 	listConfigurationsEndpoint      = "/list-configurations"
 	profileCreatedEndpoint          = "/profile-created"
 	profileNotCreatedEndpoint       = "/profile-not-created"
+	listProfilesEndpoint            = "/list-profiles"
 	listTriggersEndpoint            = "/list-triggers"
 	triggerCreatedEndpoint          = "/trigger-created"
 	triggerNotCreatedEndpoint       = "/trigger-not-created"
+	auditEndpoint                   = "/audit"
+	mustGatherRequestsEndpoint      = "/must-gather-requests"
 )
 
 // Messages
@@ -78,146 +90,13 @@ const (
 	errorCommunicatingWithServiceMessage = "Error communicating with the service"
 )
 
-var controllerURL = ""
-
-func serverCommunicationError(err error) error {
-	return fmt.Errorf("Communication error with the server %v", err)
-}
-
-func performReadRequest(url string) ([]byte, error) {
-	// #nosec G107
-	response, err := http.Get(url)
-	if err != nil {
-		return nil, serverCommunicationError(err)
-	}
-	if response.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("Expected HTTP status 200 OK, got %d", response.StatusCode)
-	}
-	body, readErr := ioutil.ReadAll(response.Body)
-	defer func() {
-		err := response.Body.Close()
-		if err != nil {
-			log.Println(err)
-		}
-	}()
-
-	if readErr != nil {
-		return nil, fmt.Errorf("Unable to read response body")
-	}
-
-	return body, nil
-}
-
-func performWriteRequest(url string, method string, payload io.Reader) error {
-	var client http.Client
+// controller is a client used to communicate with the insights operator
+// instrumentation service
+var controller client.ControllerClient
 
-	request, err := http.NewRequest(method, url, payload)
-	if err != nil {
-		return fmt.Errorf("Error creating request %v", err)
-	}
-
-	response, err := client.Do(request)
-	if err != nil {
-		return serverCommunicationError(err)
-	}
-	if response.StatusCode != http.StatusOK && response.StatusCode != http.StatusCreated && response.StatusCode != http.StatusAccepted {
-		return fmt.Errorf("Expected HTTP status 200 OK, 201 Created or 202 Accepted, got %d", response.StatusCode)
-	}
-	return nil
-}
-
-func readListOfClusters(controllerURL string, apiPrefix string) ([]types.Cluster, error) {
-	clusters := []types.Cluster{}
-
-	url := controllerURL + apiPrefix + "client/cluster"
-	body, err := performReadRequest(url)
-	if err != nil {
-		return nil, err
-	}
-
-	err = json.Unmarshal(body, &clusters)
-	if err != nil {
-		return nil, err
-	}
-	return clusters, nil
-}
-
-func readListOfConfigurationProfiles(controllerURL string, apiPrefix string) ([]types.ConfigurationProfile, error) {
-	profiles := []types.ConfigurationProfile{}
-
-	url := controllerURL + apiPrefix + "client/profile"
-	body, err := performReadRequest(url)
-	if err != nil {
-		return nil, err
-	}
-
-	err = json.Unmarshal(body, &profiles)
-	if err != nil {
-		return nil, err
-	}
-	return profiles, nil
-}
-
-func readListOfConfigurations(controllerURL string, apiPrefix string) ([]types.ClusterConfiguration, error) {
-	configurations := []types.ClusterConfiguration{}
-
-	url := controllerURL + apiPrefix + "client/configuration"
-	body, err := performReadRequest(url)
-	if err != nil {
-		return nil, err
-	}
-
-	err = json.Unmarshal(body, &configurations)
-	if err != nil {
-		return nil, err
-	}
-	return configurations, nil
-}
-
-func readListOfTriggers(controllerURL string, apiPrefix string, clusterName string) ([]types.Trigger, error) {
-	var triggers []types.Trigger
-	url := controllerURL + apiPrefix + "client/cluster/" + clusterName + "/trigger"
-	body, err := performReadRequest(url)
-	if err != nil {
-		return nil, err
-	}
-
-	err = json.Unmarshal(body, &triggers)
-	if err != nil {
-		return nil, err
-	}
-	return triggers, nil
-}
-
-func readListOfAllTriggers(controllerURL string, apiPrefix string) ([]types.Trigger, error) {
-	var triggers []types.Trigger
-	url := controllerURL + apiPrefix + "client/trigger"
-	body, err := performReadRequest(url)
-	if err != nil {
-		return nil, err
-	}
-
-	err = json.Unmarshal(body, &triggers)
-	if err != nil {
-		return nil, err
-	}
-	return triggers, nil
-}
-
-func readConfigurationProfile(controllerURL string, apiPrefix string, profileID string) (*types.ConfigurationProfile, error) {
-	var profile types.ConfigurationProfile
-	url := controllerURL + apiPrefix + "client/profile/" + profileID
-	body, err := performReadRequest(url)
-	if err != nil {
-		return nil, err
-	}
-
-	err = json.Unmarshal(body, &profile)
-	if err != nil {
-		return nil, err
-	}
-	return &profile, nil
-}
+// circuitBreaker is used by controller client to short-circuit calls when
+// the controller is down
+var circuitBreaker *client.CircuitBreaker
 
 func getContentType(filename string) string {
 	// TODO: to map
@@ -227,6 +106,8 @@ func getContentType(filename string) string {
 		return ContentTypeJavaScript
 	} else if strings.HasSuffix(filename, ".css") {
 		return ContentTypeCSS
+	} else if strings.HasSuffix(filename, ".json") {
+		return ContentTypeJSON
 	}
 	return ContentTypeHTML
 }
@@ -234,7 +115,7 @@ func getContentType(filename string) string {
 func writeResponse(writer http.ResponseWriter, message string) {
 	_, err := fmt.Fprint(writer, message)
 	if err != nil {
-		log.Println("Error sending response", err)
+		logging.Warn(context.Background(), "Error sending response", "error", err)
 	}
 }
 
@@ -247,14 +128,13 @@ func errorParsingTemplateResponse(writer http.ResponseWriter) {
 }
 
 func sendStaticPage(writer http.ResponseWriter, filename string) {
-	// #nosec G304
-	body, err := ioutil.ReadFile(filename)
+	body, err := fs.ReadFile(assets, filename)
 	if err == nil {
 		writer.Header().Set("Server", "A Go Web Server")
 		writer.Header().Set("Content-Type", getContentType(filename))
 		_, err = fmt.Fprint(writer, string(body))
 		if err != nil {
-			log.Println("Error sending response body", err)
+			logging.Warn(context.Background(), "Error sending response body", "file", filename, "error", err)
 		}
 	} else {
 		writer.WriteHeader(http.StatusNotFound)
@@ -262,73 +142,108 @@ func sendStaticPage(writer http.ResponseWriter, filename string) {
 	}
 }
 
+// renderPage renders given page template. Failures are reported to the
+// client with HTTP status 500. Requests sent to JSON API get the dynamic
+// content of the page as JSON instead, list pages can be rendered as JSON or
+// CSV when the client asks for it.
+func renderPage(writer http.ResponseWriter, request *http.Request, status int, page string, dynData interface{}) {
+	if readAt := client.StaleSince(request.Context()); !readAt.IsZero() {
+		writer.Header().Set("Warning", `110 - "Controller is unavailable, data read at `+readAt.UTC().Format(time.RFC3339)+`"`)
+	}
+	if isAPIRequest(request) {
+		writeJSON(writer, request, status, dynData)
+		return
+	}
+	if table, ok := dynData.(tabularData); ok && renderTable(writer, request, status, page, table) {
+		return
+	}
+
+	err := templates.Render(writer, request, status, page, dynData)
+	if err != nil {
+		logging.Error(request.Context(), errorExecutingTemplate, "page", page, "error", err)
+		writer.WriteHeader(http.StatusInternalServerError)
+		errorParsingTemplateResponse(writer)
+	}
+}
+
+// templatePage returns handler that renders page without dynamic content
+func templatePage(page string) func(writer http.ResponseWriter, request *http.Request) {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		renderPage(writer, request, http.StatusOK, page, nil)
+	}
+}
+
 func staticPage(filename string) func(writer http.ResponseWriter, request *http.Request) {
-	log.Println("Serving static file", filename)
+	logging.Debug(context.Background(), "Serving static file", "file", filename)
 	return func(writer http.ResponseWriter, request *http.Request) {
 		sendStaticPage(writer, filename)
 	}
 }
 
-// ListClustersDynContent represents dynamic part of HTML page with list of clusters
+// ListClustersDynContent represents dynamic part of HTML page with list of clusters.
+//
+//	Items: items displayed on the page
+//	Page: query and position of the page in list
+//	matching: all items matching the query, they are exported to JSON and CSV
 type ListClustersDynContent struct {
-	Items []types.Cluster
+	Items    []types.Cluster `json:"items"`
+	Page     listing.Page    `json:"page"`
+	matching []types.Cluster
 }
 
 func listClusters(writer http.ResponseWriter, request *http.Request) {
-	clusters, err := readListOfClusters(controllerURL, APIPrefix)
+	clusters, err := controller.ListClusters(request.Context())
 	if err != nil {
-		log.Println("Error reading list of clusters", err)
-		return
-	}
-
-	t, err := template.ParseFiles("html/list_clusters.html")
-	if err != nil {
-		writer.WriteHeader(http.StatusNotFound)
-		notFoundResponse(writer)
+		controllerErrorResponse(writer, request, client.OperationListClusters, err)
 		return
 	}
 
-	dynData := ListClustersDynContent{Items: clusters}
-	err = t.Execute(writer, dynData)
-	if err != nil {
-		println(errorExecutingTemplate)
-	}
+	dynData := newListClustersDynContent(request, clusters)
+	renderPage(writer, request, http.StatusOK, "list_clusters.html", dynData)
 }
 
-// ListProfilesDynContent represents dynamic part of HTML page with list of configuration profiles
+// ListProfilesDynContent represents dynamic part of HTML page with list of configuration profiles.
+//
+//	Items: items displayed on the page
+//	Page: query and position of the page in list
+//	matching: all items matching the query, they are exported to JSON and CSV
 type ListProfilesDynContent struct {
-	Items []types.ConfigurationProfile
+	Items    []types.ConfigurationProfile `json:"items"`
+	Page     listing.Page                 `json:"page"`
+	matching []types.ConfigurationProfile
 }
 
 func listProfiles(writer http.ResponseWriter, request *http.Request) {
-	profiles, err := readListOfConfigurationProfiles(controllerURL, APIPrefix)
-	if err != nil {
-		log.Println("Error reading list of configuration profiles", err)
-		return
-	}
-
-	t, err := template.ParseFiles("html/list_profiles.html")
+	profiles, err := controller.ListConfigurationProfiles(request.Context())
 	if err != nil {
-		writer.WriteHeader(http.StatusNotFound)
-		notFoundResponse(writer)
+		controllerErrorResponse(writer, request, client.OperationListConfigurationProfiles, err)
 		return
 	}
 
-	dynData := ListProfilesDynContent{Items: profiles}
-	err = t.Execute(writer, dynData)
-	if err != nil {
-		println(errorExecutingTemplate)
-	}
+	dynData := newListProfilesDynContent(request, profiles)
+	renderPage(writer, request, http.StatusOK, "list_profiles.html", dynData)
 }
 
-// ListConfigurationsDynContent represents dynamic part of HTML page with list of configurations
+// ListConfigurationsDynContent represents dynamic part of HTML page with list of configurations.
+//
+//	Items: items displayed on the page
+//	Page: query and position of the page in list
+//	matching: all items matching the query, they are exported to JSON and CSV
 type ListConfigurationsDynContent struct {
-	Items []types.ClusterConfiguration
+	Items    []types.ClusterConfiguration `json:"items"`
+	Page     listing.Page                 `json:"page"`
+	matching []types.ClusterConfiguration
 }
 
-// ListTriggersDynContent represents dynamic part of HTML page with list of triggers
+// ListTriggersDynContent represents dynamic part of HTML page with list of triggers.
+//
+//	Items: items displayed on the page
+//	Page: query and position of the page in list
+//	matching: all items matching the query, they are exported to JSON and CSV
 type ListTriggersDynContent struct {
-	Items []types.Trigger
+	Items    []types.Trigger `json:"items"`
+	Page     listing.Page    `json:"page"`
+	matching []types.Trigger
 }
 
 var epoch = time.Unix(0, 0).Format(time.RFC1123)
@@ -341,40 +256,37 @@ var noCacheHeaders = map[string]string{
 }
 
 func listConfigurations(writer http.ResponseWriter, request *http.Request) {
-	configurations, err := readListOfConfigurations(controllerURL, APIPrefix)
+	configurations, err := controller.ListClusterConfigurations(request.Context())
 	// NoCache headers
 	for k, v := range noCacheHeaders {
 		writer.Header().Set(k, v)
 	}
 
 	if err != nil {
-		log.Println("Error reading list of cluster configurations", err)
+		controllerErrorResponse(writer, request, client.OperationListClusterConfigurations, err)
 		return
 	}
 
-	t, err := template.ParseFiles("html/list_configurations.html")
-	if err != nil {
-		writer.WriteHeader(http.StatusNotFound)
-		notFoundResponse(writer)
-		return
-	}
-
-	dynData := ListConfigurationsDynContent{Items: configurations}
-	err = t.Execute(writer, dynData)
-	if err != nil {
-		println(errorExecutingTemplate)
-	}
+	dynData := newListConfigurationsDynContent(request, configurations)
+	renderPage(writer, request, http.StatusOK, "list_configurations.html", dynData)
 }
 
 func listTriggers(writer http.ResponseWriter, request *http.Request) {
-	clusterName, ok := request.URL.Query()["clusterName"]
+	// cluster name can be specified as path parameter or in query
+	clusterName := router.Param(request, "name")
+	if clusterName == "" {
+		clusterName = request.URL.Query().Get("clusterName")
+	}
+
 	var triggers []types.Trigger
 	var err error
+	operation := client.OperationListTriggers
 
-	if !ok {
-		triggers, err = readListOfAllTriggers(controllerURL, APIPrefix)
+	if clusterName == "" {
+		triggers, err = controller.ListTriggers(request.Context())
 	} else {
-		triggers, err = readListOfTriggers(controllerURL, APIPrefix, clusterName[0])
+		operation = client.OperationListClusterTriggers
+		triggers, err = controller.ListClusterTriggers(request.Context(), clusterName)
 	}
 
 	// NoCache headers
@@ -383,200 +295,201 @@ func listTriggers(writer http.ResponseWriter, request *http.Request) {
 	}
 
 	if err != nil {
-		log.Println("Error reading list of triggers", err)
+		controllerErrorResponse(writer, request, operation, err)
 		return
 	}
 
-	log.Println(triggers)
-	t, err := template.ParseFiles("html/list_triggers.html")
-	if err != nil {
-		writer.WriteHeader(http.StatusNotFound)
-		notFoundResponse(writer)
-		return
+	logging.Debug(request.Context(), "Triggers read from controller", "count", len(triggers))
+	dynData := newListTriggersDynContent(request, triggers)
+	renderPage(writer, request, http.StatusOK, "list_triggers.html", dynData)
+}
+
+// StatusDynContent represents dynamic part of HTML page with status of
+// communication with the controller
+type StatusDynContent struct {
+	ControllerURL string
+	Breaker       client.BreakerStatus
+}
+
+func status(writer http.ResponseWriter, request *http.Request) {
+	// NoCache headers
+	for k, v := range noCacheHeaders {
+		writer.Header().Set(k, v)
 	}
 
-	dynData := ListTriggersDynContent{Items: triggers}
-	err = t.Execute(writer, dynData)
-	if err != nil {
-		println(errorExecutingTemplate)
+	dynData := StatusDynContent{
+		ControllerURL: viper.GetString("controller_url"),
+		Breaker:       circuitBreaker.Status(),
 	}
+	renderPage(writer, request, http.StatusOK, "status.html", dynData)
 }
 
 // DescribeConfigurationDynContent represents dynamic part of HTML page with configuration description
 type DescribeConfigurationDynContent struct {
-	Configuration types.ConfigurationProfile
+	Configuration types.ConfigurationProfile `json:"configuration"`
 }
 
 func describeConfiguration(writer http.ResponseWriter, request *http.Request) {
-	configID, ok := request.URL.Query()["configuration"]
-	if !ok {
-		writer.WriteHeader(http.StatusNotFound)
-		notFoundResponse(writer)
-		return
+	// profile ID is specified as path parameter in API or in query
+	configID := router.Param(request, idParameter)
+	if configID == "" {
+		configID = request.URL.Query().Get(configurationParameter)
 	}
-
-	configuration, err := readConfigurationProfile(controllerURL, APIPrefix, configID[0])
-	fmt.Println(configuration)
-	if err != nil {
-		writer.WriteHeader(http.StatusNotFound)
-		notFoundResponse(writer)
+	if configID == "" {
+		notFound(writer, request)
 		return
 	}
 
-	t, err := template.ParseFiles("html/describe_configuration.html")
+	configuration, err := controller.GetConfigurationProfile(request.Context(), configID)
 	if err != nil {
-		writer.WriteHeader(http.StatusNotFound)
-		errorParsingTemplateResponse(writer)
+		controllerErrorResponse(writer, request, client.OperationGetConfigurationProfile, err)
 		return
 	}
 
 	dynData := DescribeConfigurationDynContent{Configuration: *configuration}
-	err = t.Execute(writer, dynData)
-	if err != nil {
-		println(errorExecutingTemplate)
-	}
+	renderPage(writer, request, http.StatusOK, "describe_configuration.html", dynData)
 }
 
 func storeProfile(writer http.ResponseWriter, request *http.Request) {
 	err := request.ParseForm()
 	if err != nil {
-		log.Println(errorHandlingFormMessage, err)
+		logging.Warn(request.Context(), errorHandlingFormMessage, "error", err)
 		notFoundResponse(writer)
 		return
 	}
 	form := request.Form
 
-	username := form.Get("username")
+	username := formUsername(request)
 	description := form.Get(descriptionParameter)
 	configuration := form.Get(configurationParameter)
 
-	log.Println("username", username)
-	log.Println(descriptionParameter, description)
-	log.Println(configurationParameter, configuration)
-
-	query := "username=" + url.QueryEscape(username) + "&description=" + url.QueryEscape(description)
-	url := controllerURL + APIPrefix + "client/profile?" + query
+	logging.Debug(request.Context(), "Storing configuration profile", "username", username, "form", logging.RedactForm(form))
 
-	err = performWriteRequest(url, http.MethodPost, strings.NewReader(configuration))
+	parameters := map[string]string{descriptionParameter: description, configurationParameter: configuration}
+	err = auditAction(request, client.OperationCreateConfigurationProfile, "", parameters, func(ctx context.Context) error {
+		return controller.CreateConfigurationProfile(ctx, username, description, configuration)
+	})
 	if err != nil {
-		log.Println(errorCommunicatingWithServiceMessage, err)
-		http.Redirect(writer, request, profileNotCreatedEndpoint, 301)
+		if needsErrorPage(err) || isAPIRequest(request) {
+			controllerErrorResponse(writer, request, client.OperationCreateConfigurationProfile, err)
+			return
+		}
+		logging.Error(request.Context(), errorCommunicatingWithServiceMessage, "error", err)
+		http.Redirect(writer, request, profileNotCreatedEndpoint, http.StatusSeeOther)
 	} else {
-		log.Println("Configuration profile has been created")
-		http.Redirect(writer, request, profileCreatedEndpoint, 301)
+		logging.Info(request.Context(), "Configuration profile has been created", "username", username)
+		actionDone(writer, request, http.StatusCreated, profileCreatedEndpoint, "Configuration profile has been created")
 	}
 }
 
 func storeConfiguration(writer http.ResponseWriter, request *http.Request) {
 	err := request.ParseForm()
 	if err != nil {
-		log.Println(errorHandlingFormMessage, err)
+		logging.Warn(request.Context(), errorHandlingFormMessage, "error", err)
 		notFoundResponse(writer)
 		return
 	}
 	form := request.Form
 
-	username := form.Get(usernameParameter)
+	username := formUsername(request)
 	cluster := form.Get(clusterParameter)
 	reason := form.Get(reasonParameter)
 	description := form.Get(descriptionParameter)
 	configuration := form.Get(configurationParameter)
 
-	log.Println(usernameParameter, username)
-	log.Println(clusterParameter, cluster)
-	log.Println(reasonParameter, reason)
-	log.Println(descriptionParameter, description)
-	log.Println(configurationParameter, configuration)
-
-	query := "username=" + url.QueryEscape(username) + "&reason=" + url.QueryEscape(reason) + "&description=" + url.QueryEscape(description)
-	url := controllerURL + APIPrefix + "client/cluster/" + url.PathEscape(cluster) + "/configuration?" + query
+	logging.Debug(request.Context(), "Storing cluster configuration", "username", username, "form", logging.RedactForm(form))
 
-	err = performWriteRequest(url, http.MethodPost, strings.NewReader(configuration))
+	parameters := map[string]string{reasonParameter: reason, descriptionParameter: description, configurationParameter: configuration}
+	err = auditAction(request, client.OperationCreateClusterConfiguration, cluster, parameters, func(ctx context.Context) error {
+		return controller.CreateClusterConfiguration(ctx, username, cluster, reason, description, configuration)
+	})
 	if err != nil {
-		log.Println(errorCommunicatingWithServiceMessage, err)
-		http.Redirect(writer, request, configurationNotCreatedEndpoint, 301)
+		if needsErrorPage(err) || isAPIRequest(request) {
+			controllerErrorResponse(writer, request, client.OperationCreateClusterConfiguration, err)
+			return
+		}
+		logging.Error(request.Context(), errorCommunicatingWithServiceMessage, "error", err)
+		http.Redirect(writer, request, configurationNotCreatedEndpoint, http.StatusSeeOther)
 	} else {
-		log.Println("Configuration has been created")
-		http.Redirect(writer, request, configurationCreatedEndpoint, 301)
+		logging.Info(request.Context(), "Configuration has been created", "username", username, "cluster", cluster)
+		actionDone(writer, request, http.StatusCreated, configurationCreatedEndpoint, "Configuration has been created")
 	}
 }
 
 func enableConfiguration(writer http.ResponseWriter, request *http.Request) {
-	configurationID, ok := request.URL.Query()["id"]
-	if !ok {
-		writer.WriteHeader(http.StatusNotFound)
-		notFoundResponse(writer)
+	configurationID := pathOrFormValue(request, idParameter)
+	if configurationID == "" {
+		notFound(writer, request)
 		return
 	}
-	url := controllerURL + APIPrefix + "client/configuration/" + configurationID[0] + "/enable"
-	err := performWriteRequest(url, http.MethodPut, nil)
+	err := auditAction(request, client.OperationEnableClusterConfiguration, configurationID, nil, func(ctx context.Context) error {
+		return controller.EnableClusterConfiguration(ctx, configurationID)
+	})
 	if err != nil {
-		fmt.Println(errorCommunicatingWithServiceMessage, err)
+		controllerErrorResponse(writer, request, client.OperationEnableClusterConfiguration, err)
 		return
 	}
 
 	// everything is ok, configuration has been enabled
-	fmt.Println("Configuration " + configurationID[0] + " has been enabled")
-	http.Redirect(writer, request, listConfigurationsEndpoint, 307)
+	logging.Info(request.Context(), "Configuration has been enabled", "configuration", configurationID)
+	actionDone(writer, request, http.StatusOK, listConfigurationsEndpoint, "Configuration has been enabled")
 }
 
 func disableConfiguration(writer http.ResponseWriter, request *http.Request) {
-	configurationID, ok := request.URL.Query()["id"]
-	if !ok {
-		writer.WriteHeader(http.StatusNotFound)
-		notFoundResponse(writer)
+	configurationID := pathOrFormValue(request, idParameter)
+	if configurationID == "" {
+		notFound(writer, request)
 		return
 	}
-	url := controllerURL + APIPrefix + "client/configuration/" + configurationID[0] + "/disable"
-	err := performWriteRequest(url, http.MethodPut, nil)
+	err := auditAction(request, client.OperationDisableClusterConfiguration, configurationID, nil, func(ctx context.Context) error {
+		return controller.DisableClusterConfiguration(ctx, configurationID)
+	})
 	if err != nil {
-		fmt.Println(errorCommunicatingWithServiceMessage, err)
+		controllerErrorResponse(writer, request, client.OperationDisableClusterConfiguration, err)
 		return
 	}
 
 	// everything is ok, configuration has been disabled
-	fmt.Println("Configuration " + configurationID[0] + " has been disabled")
-	http.Redirect(writer, request, listConfigurationsEndpoint, 307)
+	logging.Info(request.Context(), "Configuration has been disabled", "configuration", configurationID)
+	actionDone(writer, request, http.StatusOK, listConfigurationsEndpoint, "Configuration has been disabled")
 }
 
 func activateTrigger(writer http.ResponseWriter, request *http.Request) {
-	triggerID, ok := request.URL.Query()["id"]
-	if !ok {
-		writer.WriteHeader(http.StatusNotFound)
-		notFoundResponse(writer)
+	triggerID := pathOrFormValue(request, idParameter)
+	if triggerID == "" {
+		notFound(writer, request)
 		return
 	}
-	url := controllerURL + APIPrefix + "client/trigger/" + triggerID[0] + "/activate"
-
-	err := performWriteRequest(url, http.MethodPut, nil)
+	err := auditAction(request, client.OperationActivateTrigger, triggerID, nil, func(ctx context.Context) error {
+		return controller.ActivateTrigger(ctx, triggerID)
+	})
 	if err != nil {
-		fmt.Println(errorCommunicatingWithServiceMessage, err)
+		controllerErrorResponse(writer, request, client.OperationActivateTrigger, err)
 		return
 	}
 
 	// everything is ok, trigger has been activated
-	fmt.Println("Trigger " + triggerID[0] + " has been activated")
-	http.Redirect(writer, request, listTriggersEndpoint, 307)
+	logging.Info(request.Context(), "Trigger has been activated", "trigger", triggerID)
+	actionDone(writer, request, http.StatusOK, listTriggersEndpoint, "Trigger has been activated")
 }
 
 func deactivateTrigger(writer http.ResponseWriter, request *http.Request) {
-	triggerID, ok := request.URL.Query()["id"]
-	if !ok {
-		writer.WriteHeader(http.StatusNotFound)
-		notFoundResponse(writer)
+	triggerID := pathOrFormValue(request, idParameter)
+	if triggerID == "" {
+		notFound(writer, request)
 		return
 	}
-	url := controllerURL + APIPrefix + "client/trigger/" + triggerID[0] + "/deactivate"
-
-	err := performWriteRequest(url, http.MethodPut, nil)
+	err := auditAction(request, client.OperationDeactivateTrigger, triggerID, nil, func(ctx context.Context) error {
+		return controller.DeactivateTrigger(ctx, triggerID)
+	})
 	if err != nil {
-		fmt.Println(errorCommunicatingWithServiceMessage, err)
+		controllerErrorResponse(writer, request, client.OperationDeactivateTrigger, err)
 		return
 	}
 
 	// everything is ok, trigger has been deactivated
-	fmt.Println("Trigger " + triggerID[0] + " has been deactivated")
-	http.Redirect(writer, request, listTriggersEndpoint, 307)
+	logging.Info(request.Context(), "Trigger has been deactivated", "trigger", triggerID)
+	actionDone(writer, request, http.StatusOK, listTriggersEndpoint, "Trigger has been deactivated")
 }
 
 func triggerMustGatherConfiguration(writer http.ResponseWriter, request *http.Request) {
@@ -600,93 +513,174 @@ func triggerMustGatherConfiguration(writer http.ResponseWriter, request *http.Re
 		return
 	}
 
-	t, err := template.ParseFiles("html/trigger_must_gather.html")
-	if err != nil {
-		writer.WriteHeader(http.StatusNotFound)
-		errorParsingTemplateResponse(writer)
-		return
-	}
-	dynData := types.Cluster{ID: id, Name: clusterName[0]}
-	err = t.Execute(writer, dynData)
-	if err != nil {
-		println(errorExecutingTemplate)
-	}
+	dynData := MustGatherForm{ClusterID: strconv.Itoa(id), ClusterName: clusterName[0]}
+	renderPage(writer, request, http.StatusOK, "trigger_must_gather.html", dynData)
 }
 
 // POST must-gather to REST API
 func triggerMustGather(writer http.ResponseWriter, request *http.Request) {
 	err := request.ParseForm()
 	if err != nil {
-		log.Println(errorHandlingFormMessage, err)
+		logging.Warn(request.Context(), errorHandlingFormMessage, "error", err)
 		notFoundResponse(writer)
 		return
 	}
 	form := request.Form
 
-	clusterID := form.Get("clusterid")
-	clusterName := form.Get("clustername")
-	username := form.Get("username")
-	reason := form.Get(reasonParameter)
-	link := form.Get(linkParameter)
+	mustGatherForm := MustGatherForm{
+		ClusterID:   strings.TrimSpace(form.Get(clusterIDParameter)),
+		ClusterName: strings.TrimSpace(form.Get(clusterNameParameter)),
+		Username:    strings.TrimSpace(form.Get(usernameParameter)),
+		Reason:      strings.TrimSpace(form.Get(reasonParameter)),
+		Link:        strings.TrimSpace(form.Get(linkParameter)),
+	}
+	mustGatherForm.validate(mustGatherConfiguration)
+	if !mustGatherForm.Valid() {
+		logging.Info(request.Context(), "Invalid must-gather form", "errors", mustGatherForm.Errors)
+		renderPage(writer, request, http.StatusBadRequest, "trigger_must_gather.html", mustGatherForm)
+		return
+	}
 
-	log.Println("clusterID", clusterID)
-	log.Println("clusterName", clusterName)
-	log.Println("username", username)
-	log.Println(reasonParameter, reason)
-	log.Println(linkParameter, link)
+	clusterID := mustGatherForm.ClusterID
+	clusterName := mustGatherForm.ClusterName
+	username := formUsername(request)
+	reason := mustGatherForm.Reason
+	link := mustGatherForm.Link
 
-	query := "username=" + url.QueryEscape(username) + "&reason=" + url.QueryEscape(reason) + "&link=" + url.QueryEscape(link)
-	log.Println(query)
-	url := controllerURL + APIPrefix + "client/cluster/" + url.PathEscape(clusterName) + "/trigger/must-gather?" + query
-	log.Println(url)
+	logging.Debug(request.Context(), "Requesting must-gather", "username", username, "form", logging.RedactForm(form))
 
-	err = performWriteRequest(url, http.MethodPost, nil)
+	// must-gather is sent to the controller once it has been approved
+	if mustGatherRequests != nil {
+		submitMustGather(writer, request, approval.Request{
+			ClusterID:   clusterID,
+			ClusterName: clusterName,
+			Reason:      reason,
+			Link:        link,
+			RequestedBy: username,
+		})
+		return
+	}
+
+	parameters := map[string]string{reasonParameter: reason, linkParameter: link}
+	err = auditAction(request, client.OperationTriggerMustGather, clusterName, parameters, func(ctx context.Context) error {
+		return controller.TriggerMustGather(ctx, clusterName, username, reason, link)
+	})
 	if err != nil {
-		log.Println(errorCommunicatingWithServiceMessage, err)
-		http.Redirect(writer, request, triggerNotCreatedEndpoint, 301)
+		if needsErrorPage(err) || isAPIRequest(request) {
+			controllerErrorResponse(writer, request, client.OperationTriggerMustGather, err)
+			return
+		}
+		logging.Error(request.Context(), errorCommunicatingWithServiceMessage, "error", err)
+		http.Redirect(writer, request, triggerNotCreatedEndpoint, http.StatusSeeOther)
 	} else {
-		log.Println("Trigger has been created")
-		http.Redirect(writer, request, triggerCreatedEndpoint, 301)
-	}
-}
-
-func startHTTPServer(address string) {
-	http.HandleFunc("/", staticPage("html/index.html"))
-	http.HandleFunc("/bootstrap.min.css", staticPage("html/bootstrap.min.css"))
-	http.HandleFunc("/bootstrap.min.js", staticPage("html/bootstrap.min.js"))
-	http.HandleFunc("/ccx.css", staticPage("html/ccx.css"))
-	http.HandleFunc(configurationCreatedEndpoint, staticPage("html/configuration_created.html"))
-	http.HandleFunc(configurationNotCreatedEndpoint, staticPage("html/configuration_not_created.html"))
-	http.HandleFunc(profileCreatedEndpoint, staticPage("html/profile_created.html"))
-	http.HandleFunc(profileNotCreatedEndpoint, staticPage("html/profile_not_created.html"))
-	http.HandleFunc("/list-clusters", listClusters)
-	http.HandleFunc("/list-profiles", listProfiles)
-	http.HandleFunc(listConfigurationsEndpoint, listConfigurations)
-	http.HandleFunc("/list-all-triggers", listTriggers)
-	http.HandleFunc(listTriggersEndpoint, listTriggers)
-	http.HandleFunc("/describe-configuration", describeConfiguration)
-	http.HandleFunc("/new-profile", staticPage("html/new_profile.html"))
-	http.HandleFunc("/new-configuration", staticPage("html/new_configuration.html"))
-	http.HandleFunc("/store-profile", storeProfile)
-	http.HandleFunc("/store-configuration", storeConfiguration)
-	http.HandleFunc("/enable-configuration", enableConfiguration)
-	http.HandleFunc("/disable-configuration", disableConfiguration)
-	http.HandleFunc("/activate-trigger", activateTrigger)
-	http.HandleFunc("/deactivate-trigger", deactivateTrigger)
-	http.HandleFunc("/trigger-must-gather-configuration", triggerMustGatherConfiguration)
-	http.HandleFunc("/trigger-must-gather", triggerMustGather)
-	http.HandleFunc(triggerCreatedEndpoint, staticPage("html/trigger_created.html"))
-	http.HandleFunc(triggerNotCreatedEndpoint, staticPage("html/trigger_not_created.html"))
-
-	// try to start the server
-	err := http.ListenAndServe(address, nil)
-	if err != nil {
-		log.Fatal(err)
+		logging.Info(request.Context(), "Must-gather has been triggered", "cluster", clusterName, "username", username)
+		actionDone(writer, request, http.StatusCreated, triggerCreatedEndpoint, "Must-gather has been triggered")
+	}
+}
+
+// notFound is used for all requests that don't match any route
+func notFound(writer http.ResponseWriter, request *http.Request) {
+	if isAPIRequest(request) {
+		errorPageResponse(writer, request, http.StatusNotFound, errorPageTemplate, ErrorPageDynContent{
+			Title:     "Not found",
+			Operation: request.Method + " " + request.URL.Path,
+		})
+		return
+	}
+	writer.WriteHeader(http.StatusNotFound)
+	notFoundResponse(writer)
+}
+
+// newRouter registers all handlers together with HTTP methods they accept
+// and permissions users need to access them. State changing endpoints accept
+// POST requests only.
+func newRouter() *router.Router {
+	r := router.New()
+	r.NotFound = instrumentRoute(unmatchedRoute)(http.HandlerFunc(notFound))
+
+	canRead := requirePermission(auth.PermissionRead)
+	canWrite := requirePermission(auth.PermissionWrite)
+	canMustGather := requirePermission(auth.PermissionMustGather)
+	canApproveMustGather := requirePermission(auth.PermissionApproveMustGather)
+
+	r.HandleFunc("/", templatePage("index.html"), http.MethodGet).With(canRead)
+	r.HandleFunc("/bootstrap.min.css", staticPage("bootstrap.min.css"), http.MethodGet)
+	r.HandleFunc("/bootstrap.min.js", staticPage("bootstrap.min.js"), http.MethodGet)
+	r.HandleFunc("/ccx.css", staticPage("ccx.css"), http.MethodGet)
+	r.HandleFunc(configurationCreatedEndpoint, templatePage("configuration_created.html"), http.MethodGet).With(canWrite)
+	r.HandleFunc(configurationNotCreatedEndpoint, templatePage("configuration_not_created.html"), http.MethodGet).With(canWrite)
+	r.HandleFunc(profileCreatedEndpoint, templatePage("profile_created.html"), http.MethodGet).With(canWrite)
+	r.HandleFunc(profileNotCreatedEndpoint, templatePage("profile_not_created.html"), http.MethodGet).With(canWrite)
+	r.HandleFunc("/list-clusters", listClusters, http.MethodGet).With(canRead)
+	r.HandleFunc(listProfilesEndpoint, listProfiles, http.MethodGet).With(canRead)
+	r.HandleFunc(listConfigurationsEndpoint, listConfigurations, http.MethodGet).With(canRead)
+	r.HandleFunc("/list-all-triggers", listTriggers, http.MethodGet).With(canRead)
+	r.HandleFunc(listTriggersEndpoint, listTriggers, http.MethodGet).With(canRead)
+	r.HandleFunc("/clusters/{name}", clusterDetail, http.MethodGet).With(canRead)
+	r.HandleFunc("/clusters/{name}/triggers", listTriggers, http.MethodGet).With(canRead)
+	r.HandleFunc("/describe-configuration", describeConfiguration, http.MethodGet).With(canRead)
+	r.HandleFunc("/new-profile", newProfile, http.MethodGet).With(canWrite)
+	r.HandleFunc("/edit-profile", editProfile, http.MethodGet).With(canWrite)
+	r.HandleFunc("/delete-profile", deleteProfileConfirmation, http.MethodGet).With(canWrite)
+	r.HandleFunc("/new-configuration", newConfiguration, http.MethodGet).With(canWrite)
+	r.HandleFunc("/store-profile", storeProfile, http.MethodPost).With(canWrite)
+	r.HandleFunc("/update-profile", updateProfile, http.MethodPost).With(canWrite)
+	r.HandleFunc("/delete-profile", deleteProfile, http.MethodPost).With(canWrite)
+	r.HandleFunc("/store-configuration", storeConfiguration, http.MethodPost).With(canWrite)
+	r.HandleFunc("/enable-configuration", enableConfiguration, http.MethodPost).With(canWrite)
+	r.HandleFunc("/disable-configuration", disableConfiguration, http.MethodPost).With(canWrite)
+	r.HandleFunc("/activate-trigger", activateTrigger, http.MethodPost).With(canWrite)
+	r.HandleFunc("/deactivate-trigger", deactivateTrigger, http.MethodPost).With(canWrite)
+	r.HandleFunc("/trigger-must-gather-configuration", triggerMustGatherConfiguration, http.MethodGet).With(canMustGather)
+	r.HandleFunc("/trigger-must-gather", triggerMustGather, http.MethodPost).With(canMustGather)
+	r.HandleFunc(triggerCreatedEndpoint, templatePage("trigger_created.html"), http.MethodGet).With(canMustGather)
+	r.HandleFunc(triggerNotCreatedEndpoint, templatePage("trigger_not_created.html"), http.MethodGet).With(canMustGather)
+	r.HandleFunc(mustGatherRequestsEndpoint, mustGatherRequestsPage, http.MethodGet).With(canRead)
+	r.HandleFunc(mustGatherRequestsEndpoint+"/{id}", mustGatherRequestPage, http.MethodGet).With(canRead)
+	r.HandleFunc(mustGatherRequestsEndpoint+"/{id}/approve", approveMustGather, http.MethodPost).With(canApproveMustGather)
+	r.HandleFunc(mustGatherRequestsEndpoint+"/{id}/reject", rejectMustGather, http.MethodPost).With(canApproveMustGather)
+	r.HandleFunc(mustGatherRequestsEndpoint+"/{id}/cancel", cancelMustGather, http.MethodPost).With(canMustGather)
+	r.HandleFunc("/status", status, http.MethodGet).With(canRead)
+	r.HandleFunc(auditEndpoint, auditPage, http.MethodGet).With(requirePermission(auth.PermissionAudit))
+	r.Handle(metricsEndpoint, metricsRegistry, http.MethodGet)
+	r.HandleFunc(healthzEndpoint, healthz, http.MethodGet)
+	r.HandleFunc(readyzEndpoint, readyz, http.MethodGet)
+
+	if loginEnabled() {
+		r.HandleFunc(loginEndpoint, login, http.MethodGet)
+		r.HandleFunc(callbackPath(loginConfiguration.RedirectURL), loginCallback, http.MethodGet)
+		r.HandleFunc(logoutEndpoint, logout, http.MethodPost)
+	}
+
+	registerUIAPI(r)
+
+	for _, route := range r.Routes() {
+		route.With(instrumentRoute(route.Pattern()))
+	}
+	return r
+}
+
+// newHandler returns router wrapped by middleware used for all requests
+func newHandler() http.Handler {
+	return withRequestID(withStaleMarker(requireLogin(verifyCSRF(newRouter()))))
+}
+
+// checkControllerTLS performs TLS handshake with the controller and reports
+// problems with certificates. The service is started anyway, as the
+// controller itself might not be running yet.
+func checkControllerTLS(controllerClient *client.HTTPControllerClient) {
+	ctx, cancel := context.WithTimeout(context.Background(), client.DefaultTimeout)
+	defer cancel()
+
+	err := controllerClient.CheckTLSConnection(ctx)
+	if err != nil {
+		logging.Warn(ctx, "Controller connection check failed", "error", err)
 	}
 }
 
 func main() {
-	log.Println("Reading configuration")
+	ctx := context.Background()
+	logging.Info(ctx, "Reading configuration")
 	configFile, specified := os.LookupEnv("INSIGHTS_WEB_UI_CONFIG_FILE")
 	if specified {
 		// we need to separate the directory name and filename without extension
@@ -706,9 +700,71 @@ func main() {
 		panic(fmt.Errorf("Fatal error config file: %s", err))
 	}
 
-	controllerURL = viper.GetString("controller_url")
-	address := viper.GetString("address")
+	err = logging.Configure(readLoggingConfiguration())
+	if err != nil {
+		logging.Fatal(ctx, "Invalid logging configuration", "error", err)
+	}
+	// errors reported by HTTP server and other libraries go to the same log
+	log.SetFlags(0)
+	log.SetOutput(logging.Writer(logging.LevelWarn))
+
+	loadFromDisk := viper.GetBool(assetsLoadFromDiskKey)
+	err = initAssets(loadFromDisk, viper.GetString(assetsDirectoryKey))
+	if err != nil {
+		logging.Fatal(ctx, "Unable to start the service", "error", err)
+	}
 
-	log.Println("Starting the service at address: " + address)
-	startHTTPServer(address)
+	// templates loaded from disk are reloaded when changed
+	templates, err = NewTemplateRegistry(assets, loadFromDisk, templateFuncs)
+	if err != nil {
+		logging.Fatal(ctx, "Unable to start the service", "error", err)
+	}
+
+	err = initLogin(readOIDCConfiguration())
+	if err != nil {
+		logging.Fatal(ctx, "Unable to start the service", "error", err)
+	}
+
+	err = initAuthorization(readAuthorizationConfiguration())
+	if err != nil {
+		logging.Fatal(ctx, "Unable to start the service", "error", err)
+	}
+
+	err = initAudit(readAuditConfiguration())
+	if err != nil {
+		logging.Fatal(ctx, "Unable to start the service", "error", err)
+	}
+
+	err = initMustGather(readMustGatherConfiguration())
+	if err != nil {
+		logging.Fatal(ctx, "Unable to start the service", "error", err)
+	}
+
+	circuitBreaker = readCircuitBreaker()
+	err = initMetrics(circuitBreaker)
+	if err != nil {
+		logging.Fatal(ctx, "Unable to start the service", "error", err)
+	}
+
+	controllerClient, err := client.NewHTTPControllerClient(client.Configuration{
+		URL:            viper.GetString("controller_url"),
+		APIPrefix:      APIPrefix,
+		Timeouts:       readControllerTimeouts(),
+		Retry:          readRetryPolicy(),
+		CircuitBreaker: circuitBreaker,
+		CacheSize:      viper.GetInt(breakerCacheSizeKey),
+		TLS:            readControllerTLSConfiguration(),
+		Auth:           readControllerAuthConfiguration(),
+	})
+	if err != nil {
+		logging.Fatal(ctx, "Unable to start the service", "error", err)
+	}
+	controller = controllerClient
+	checkControllerTLS(controllerClient)
+	initHealth(readHealthConfiguration())
+
+	err = startHTTPServer(readServerConfiguration())
+	if err != nil {
+		logging.Fatal(ctx, "Unable to start the service", "error", err)
+	}
 }